@@ -11,6 +11,8 @@ import (
 type Node interface {
 	TokenLiteral() string // デバッグ用
 	String() string       // デバッグ用
+	Start() Position      // ノードの最初のトークンの位置
+	End() Position        // ノードの最後のトークンの位置
 }
 
 // 文インターフェース、文は値を生成しない
@@ -114,13 +116,31 @@ func (b *Boolean) String() string {
 
 // -----------------------------------------------------
 
+// nullリテラル
+
+type NullLiteral struct {
+	Token token.Token // 'null' トークン
+}
+
+func (nl *NullLiteral) expressionNode() {
+}
+func (nl *NullLiteral) TokenLiteral() string {
+	return nl.Token.Literal
+}
+func (nl *NullLiteral) String() string {
+	return nl.Token.Literal
+}
+
+// -----------------------------------------------------
+
 // let文
 // 構造: let <identifier> = <expression>;
 
 type LetStatement struct {
-	Token token.Token // token.LET トークン
-	Name  *Identifier
-	Value Expression
+	Token     token.Token // token.LET トークン
+	Name      *Identifier
+	Value     Expression
+	Semicolon token.Token // 末尾の';'トークン。省略された場合はゼロ値
 }
 
 func (ls *LetStatement) statementNode() {
@@ -146,12 +166,53 @@ func (ls *LetStatement) String() string {
 
 // -----------------------------------------------------
 
+// 複数の名前をまとめて束縛するlet文
+// 構造: let <identifier>, <identifier>, ... = <expression>, <expression>, ...;
+// 右辺をすべて評価してから束縛するので、let a, b = b, a; は外側のaとbを入れ替えた値になる
+// 名前1つの let <identifier> = <expression>; は LetStatement のまま
+
+type MultiLetStatement struct {
+	Token     token.Token // token.LET トークン
+	Names     []*Identifier
+	Values    []Expression // Namesと同じ数
+	Semicolon token.Token  // 末尾の';'トークン。省略された場合はゼロ値
+}
+
+func (ms *MultiLetStatement) statementNode() {
+}
+func (ms *MultiLetStatement) TokenLiteral() string {
+	return ms.Token.Literal
+}
+func (ms *MultiLetStatement) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, n := range ms.Names {
+		names = append(names, n.String())
+	}
+	values := []string{}
+	for _, v := range ms.Values {
+		values = append(values, v.String())
+	}
+
+	out.WriteString(ms.TokenLiteral() + " ")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(" = ")
+	out.WriteString(strings.Join(values, ", "))
+	out.WriteString(";")
+
+	return out.String()
+}
+
+// -----------------------------------------------------
+
 // return文
 // 構造: return <expression>;
 
 type ReturnStatement struct {
 	Token       token.Token // 'return' トークン
 	ReturnValue Expression
+	Semicolon   token.Token // 末尾の';'トークン。省略された場合はゼロ値
 }
 
 func (rs *ReturnStatement) statementNode() {
@@ -175,11 +236,116 @@ func (rs *ReturnStatement) String() string {
 
 // -----------------------------------------------------
 
+// while文
+// 構造: while (<condition>) <block statement>
+
+type WhileStatement struct {
+	Token     token.Token // 'while' トークン
+	Condition Expression
+	Body      *BlockStatement
+}
+
+func (ws *WhileStatement) statementNode() {
+}
+func (ws *WhileStatement) TokenLiteral() string {
+	return ws.Token.Literal
+}
+func (ws *WhileStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("while (")
+	out.WriteString(ws.Condition.String())
+	out.WriteString(") { ")
+	out.WriteString(ws.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// -----------------------------------------------------
+
+// for文
+// 構造: for (<init>; <condition>; <post>) <block statement>
+// init, condition, post はいずれも省略できる(nil)
+
+type ForStatement struct {
+	Token     token.Token // 'for' トークン
+	Init      Statement
+	Condition Expression
+	Post      Statement
+	Body      *BlockStatement
+}
+
+func (fs *ForStatement) statementNode() {
+}
+func (fs *ForStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+func (fs *ForStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	if fs.Init != nil {
+		// let文のString()は末尾に";"を含むため取り除く
+		out.WriteString(strings.TrimSuffix(fs.Init.String(), ";"))
+	}
+	out.WriteString(";")
+	if fs.Condition != nil {
+		out.WriteString(" " + fs.Condition.String())
+	}
+	out.WriteString(";")
+	if fs.Post != nil {
+		out.WriteString(" " + strings.TrimSuffix(fs.Post.String(), ";"))
+	}
+	out.WriteString(") { ")
+	out.WriteString(fs.Body.String())
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+// -----------------------------------------------------
+
+// break文, continue文
+// 構造: break; / continue;
+// ループの外でも構文としては受け付け、ループ内かどうかの検査は後段で行う
+
+type BreakStatement struct {
+	Token     token.Token // 'break' トークン
+	Semicolon token.Token // 末尾の';'トークン。省略された場合はゼロ値
+}
+
+func (bs *BreakStatement) statementNode() {
+}
+func (bs *BreakStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+func (bs *BreakStatement) String() string {
+	return bs.TokenLiteral() + ";"
+}
+
+type ContinueStatement struct {
+	Token     token.Token // 'continue' トークン
+	Semicolon token.Token // 末尾の';'トークン。省略された場合はゼロ値
+}
+
+func (cs *ContinueStatement) statementNode() {
+}
+func (cs *ContinueStatement) TokenLiteral() string {
+	return cs.Token.Literal
+}
+func (cs *ContinueStatement) String() string {
+	return cs.TokenLiteral() + ";"
+}
+
+// -----------------------------------------------------
+
 // block文
 
 type BlockStatement struct {
-	Token      token.Token // トークン
+	Token      token.Token // '{' トークン
 	Statements []Statement
+	Rbrace     token.Token // '}' トークン
 }
 
 func (bs *BlockStatement) statementNode() {
@@ -207,6 +373,7 @@ func (bs *BlockStatement) String() string {
 type ExpressionStatement struct {
 	Token      token.Token // 式の最初のトークン
 	Expression Expression
+	Semicolon  token.Token // 末尾の';'トークン。省略された場合はゼロ値
 }
 
 func (es *ExpressionStatement) statementNode() {
@@ -275,6 +442,172 @@ func (oe *InfixExpression) String() string {
 	return out.String()
 }
 
+// 比較演算子(== != < >)かどうか。結果は真偽値になる
+func (oe *InfixExpression) IsComparison() bool {
+	switch oe.Operator {
+	case "==", "!=", "<", ">":
+		return true
+	}
+	return false
+}
+
+// 算術演算子(+ - * / **)かどうか
+// + は文字列の連結にも使われるので、結果が整数とは限らない
+func (oe *InfixExpression) IsArithmetic() bool {
+	switch oe.Operator {
+	case "+", "-", "*", "/", "**":
+		return true
+	}
+	return false
+}
+
+// 論理演算子(&& ||)かどうか。右辺は短絡評価される
+func (oe *InfixExpression) IsLogical() bool {
+	switch oe.Operator {
+	case "&&", "||":
+		return true
+	}
+	return false
+}
+
+// -----------------------------------------------------
+
+// 比較の連鎖
+// 構造: <expression> <比較演算子> <expression> <比較演算子> <expression> ...
+// a < b < c は (a < b) && (b < c) と同じ結果になるが、中間の項 b は1回だけ評価される
+// 左から順に比較し、偽になった時点で残りの項は評価しない
+
+type ComparisonChain struct {
+	Token     token.Token  // 最初の比較演算子のトークン
+	Operands  []Expression // Operatorsより1つ多い
+	Operators []string
+}
+
+func (cc *ComparisonChain) expressionNode() {
+}
+func (cc *ComparisonChain) TokenLiteral() string {
+	return cc.Token.Literal
+}
+func (cc *ComparisonChain) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	for i, operand := range cc.Operands {
+		if i > 0 {
+			out.WriteString(" " + cc.Operators[i-1] + " ")
+		}
+		out.WriteString(operand.String())
+	}
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// -----------------------------------------------------
+
+// 後置式
+// 構造: <identifier><operator>
+// i++ は i を1増やし、増やす前の値を生成する
+
+type PostfixExpression struct {
+	Token    token.Token // '++' または '--' トークン
+	Operator string
+	Left     Expression
+}
+
+func (pe *PostfixExpression) expressionNode() {
+}
+func (pe *PostfixExpression) TokenLiteral() string {
+	return pe.Token.Literal
+}
+func (pe *PostfixExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(pe.Left.String())
+	out.WriteString(pe.Operator)
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// -----------------------------------------------------
+
+// 代入式
+// 構造: <identifier> = <expression>
+// 代入式は代入した値を生成するため、x = y = 3 のように連鎖できる
+
+type AssignExpression struct {
+	Token token.Token // '=' トークン
+	Name  *Identifier
+	Value Expression
+}
+
+func (ae *AssignExpression) expressionNode() {
+}
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Name.String())
+	out.WriteString(" = ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// -----------------------------------------------------
+
+// 三項演算子式
+// 構造: <condition> ? <consequence> : <alternative>
+
+type TernaryExpression struct {
+	Token       token.Token // '?' トークン
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (te *TernaryExpression) expressionNode() {
+}
+func (te *TernaryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+func (te *TernaryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ternaryPartString(te.Condition))
+	out.WriteString(" ? ")
+	out.WriteString(ternaryPartString(te.Consequence))
+	out.WriteString(" : ")
+	out.WriteString(ternaryPartString(te.Alternative))
+	out.WriteString(")")
+
+	return out.String()
+}
+
+// 三項演算子の各部分の文字列表現
+// 二項演算子の式は ? や : より強く結びつくので、外側の括弧を省いて (a > b ? a : b) とする
+func ternaryPartString(e Expression) string {
+	s := e.String()
+	if isBinaryOperation(e) {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// 括弧で囲んで文字列化される二項演算子の式かどうか
+func isBinaryOperation(e Expression) bool {
+	switch e.(type) {
+	case *InfixExpression, *ComparisonChain:
+		return true
+	default:
+		return false
+	}
+}
+
 // -----------------------------------------------------
 
 // if式
@@ -308,12 +641,33 @@ func (ie *IfExpression) String() string {
 
 // -----------------------------------------------------
 
+// ブロック式
+// 構造: { <statements> }
+// 最後の文の値がブロック式の値になる。{ x } はハッシュではなくブロック式
+
+type BlockExpression struct {
+	Token token.Token // '{' トークン
+	Block *BlockStatement
+}
+
+func (be *BlockExpression) expressionNode() {
+}
+func (be *BlockExpression) TokenLiteral() string {
+	return be.Token.Literal
+}
+func (be *BlockExpression) String() string {
+	return "{" + be.Block.String() + "}"
+}
+
+// -----------------------------------------------------
+
 // 関数リテラル
 
 type FunctionLiteral struct {
 	Token      token.Token // 'fn' トークン
 	Parameters []*Identifier
 	Body       *BlockStatement
+	Name       string // let で束縛された場合の名前(デバッグ用)。無名関数では空
 }
 
 func (fl *FunctionLiteral) expressionNode() {
@@ -330,6 +684,9 @@ func (fl *FunctionLiteral) String() string {
 	}
 
 	out.WriteString(fl.TokenLiteral())
+	if fl.Name != "" {
+		out.WriteString("<" + fl.Name + ">")
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ","))
 	out.WriteString(")")
@@ -346,6 +703,7 @@ type CallExpression struct {
 	Token     token.Token // '(' トークン
 	Function  Expression  // Identifier または FunctionLiteral
 	Arguments []Expression
+	Rparen    token.Token // ')' トークン
 }
 
 func (ce *CallExpression) expressionNode() {
@@ -371,6 +729,26 @@ func (ce *CallExpression) String() string {
 
 // -----------------------------------------------------
 
+// 引数の展開
+// 構造: ...<expression>
+// 関数呼び出しの引数でのみ使え、配列の要素を個別の引数として渡す
+
+type SpreadExpression struct {
+	Token token.Token // '...' トークン
+	Value Expression
+}
+
+func (se *SpreadExpression) expressionNode() {
+}
+func (se *SpreadExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+func (se *SpreadExpression) String() string {
+	return "..." + se.Value.String()
+}
+
+// -----------------------------------------------------
+
 // 文字列リテラル
 
 type StringLiteral struct {
@@ -385,10 +763,14 @@ func (sl *StringLiteral) TokenLiteral() string {
 	return sl.Token.Literal
 }
 
+// 字句解析器が解釈するエスケープシーケンスに戻して、そのままパースできる形で出力する
 func (sl *StringLiteral) String() string {
-	return sl.Token.Literal
+	return "\"" + stringEscaper.Replace(sl.Token.Literal) + "\""
 }
 
+// 字句解析器が解釈するエスケープシーケンス(\n, \t, \", \\)の逆変換
+var stringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+
 // -----------------------------------------------------
 
 // 配列リテラル
@@ -396,6 +778,7 @@ func (sl *StringLiteral) String() string {
 type ArrayLiteral struct {
 	Token    token.Token // '['トークン
 	Elements []Expression
+	Rbracket token.Token // ']'トークン
 }
 
 func (al *ArrayLiteral) expressionNode() {
@@ -422,9 +805,10 @@ func (al *ArrayLiteral) String() string {
 // 添字演算子式の構文解析
 
 type IndexExpression struct {
-	Token token.Token // '['トークン
-	Left  Expression
-	Index Expression
+	Token    token.Token // '['トークン(h.key の場合は'.'トークン)
+	Left     Expression
+	Index    Expression
+	Rbracket token.Token // ']'トークン。h.key の場合はゼロ値
 }
 
 func (ie *IndexExpression) expressionNode() {
@@ -449,8 +833,15 @@ func (ie *IndexExpression) String() string {
 // ハッシュ・リテラル
 
 type HashLiteral struct {
-	Token token.Token // '{' トークン
-	Pairs map[Expression]Expression
+	Token  token.Token // '{' トークン
+	Pairs  []HashPair  // ソース上の順序で並べたキーと値の組
+	Rbrace token.Token // '}' トークン
+}
+
+// ハッシュ・リテラルのキーと値の組
+type HashPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) expressionNode() {
@@ -461,8 +852,8 @@ func (hl *HashLiteral) TokenLiteral() string {
 func (hl *HashLiteral) String() string {
 	var out bytes.Buffer
 	pairs := []string{}
-	for key, value := range hl.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
 	}
 	out.WriteString("{")
 	out.WriteString(strings.Join(pairs, ", "))
@@ -36,3 +36,40 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestInfixOperatorClasses(t *testing.T) {
+	tests := []struct {
+		operator   string
+		comparison bool
+		arithmetic bool
+		logical    bool
+	}{
+		{"+", false, true, false},
+		{"-", false, true, false},
+		{"*", false, true, false},
+		{"/", false, true, false},
+		{"**", false, true, false},
+		{"==", true, false, false},
+		{"!=", true, false, false},
+		{"<", true, false, false},
+		{">", true, false, false},
+		{"&&", false, false, true},
+		{"||", false, false, true},
+		// 中置式の演算子ではないもの
+		{"=", false, false, false},
+		{"!", false, false, false},
+	}
+
+	for _, tt := range tests {
+		exp := &InfixExpression{Operator: tt.operator}
+		if exp.IsComparison() != tt.comparison {
+			t.Errorf("%q: IsComparison() wrong. want=%t", tt.operator, tt.comparison)
+		}
+		if exp.IsArithmetic() != tt.arithmetic {
+			t.Errorf("%q: IsArithmetic() wrong. want=%t", tt.operator, tt.arithmetic)
+		}
+		if exp.IsLogical() != tt.logical {
+			t.Errorf("%q: IsLogical() wrong. want=%t", tt.operator, tt.logical)
+		}
+	}
+}
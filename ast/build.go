@@ -0,0 +1,81 @@
+package ast
+
+import (
+	"strconv"
+
+	"github.com/tamurayoshiya/monkey/token"
+)
+
+// 期待するASTを組み立てるためのコンストラクタ
+// 位置を持たない合成トークンを使うので、Equalでの比較やString()での表示に向いている
+// 構文解析の結果と位置まで比べる用途には使えない
+
+func Int(value int64) *IntegerLiteral {
+	literal := strconv.FormatInt(value, 10)
+	return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: literal}, Value: value}
+}
+
+func Str(value string) *StringLiteral {
+	return &StringLiteral{Token: token.Token{Type: token.STRING, Literal: value}, Value: value}
+}
+
+func Bool(value bool) *Boolean {
+	if value {
+		return &Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+	}
+	return &Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}
+}
+
+func Ident(name string) *Identifier {
+	return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+// 演算子の表記とトークンタイプが同じであることを利用して、トークンを演算子から作る
+func Prefix(operator string, right Expression) *PrefixExpression {
+	tok := token.Token{Type: token.TokenType(operator), Literal: operator}
+	return &PrefixExpression{Token: tok, Operator: operator, Right: right}
+}
+
+func Infix(left Expression, operator string, right Expression) *InfixExpression {
+	tok := token.Token{Type: token.TokenType(operator), Literal: operator}
+	return &InfixExpression{Token: tok, Left: left, Operator: operator, Right: right}
+}
+
+func Call(function Expression, arguments ...Expression) *CallExpression {
+	return &CallExpression{
+		Token:     token.Token{Type: token.LPAREN, Literal: "("},
+		Function:  function,
+		Arguments: arguments,
+		Rparen:    token.Token{Type: token.RPAREN, Literal: ")"},
+	}
+}
+
+func Index(left, index Expression) *IndexExpression {
+	return &IndexExpression{
+		Token:    token.Token{Type: token.LBRACKET, Literal: "["},
+		Left:     left,
+		Index:    index,
+		Rbracket: token.Token{Type: token.RBRACKET, Literal: "]"},
+	}
+}
+
+func Array(elements ...Expression) *ArrayLiteral {
+	return &ArrayLiteral{
+		Token:    token.Token{Type: token.LBRACKET, Literal: "["},
+		Elements: elements,
+		Rbracket: token.Token{Type: token.RBRACKET, Literal: "]"},
+	}
+}
+
+func Let(name string, value Expression) *LetStatement {
+	return &LetStatement{Token: token.Token{Type: token.LET, Literal: "let"}, Name: Ident(name), Value: value}
+}
+
+// 式文。トークンは式の先頭のトークンになるが、合成トークンでは区別しないので空にしておく
+func ExprStmt(expression Expression) *ExpressionStatement {
+	return &ExpressionStatement{Expression: expression}
+}
+
+func Prog(statements ...Statement) *Program {
+	return &Program{Statements: statements}
+}
@@ -0,0 +1,29 @@
+package ast
+
+import "testing"
+
+func TestConstructors(t *testing.T) {
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{Int(5), "5"},
+		{Int(-3), "-3"},
+		{Str("hi"), `"hi"`},
+		{Bool(true), "true"},
+		{Ident("x"), "x"},
+		{Infix(Int(1), "+", Int(2)), "(1 + 2)"},
+		{Prefix("-", Ident("a")), "(-a)"},
+		{Call(Ident("f"), Ident("a"), Int(1)), "f(a, 1)"},
+		{Index(Ident("a"), Int(0)), "(a[0])"},
+		{Array(Int(1), Int(2)), "[1, 2]"},
+		{Let("x", Infix(Ident("a"), "*", Int(2))), "let x = (a * 2);"},
+		{Prog(ExprStmt(Ident("x")), Let("y", Int(1))), "xlet y = 1;"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.node.String(); got != tt.expected {
+			t.Errorf("String() wrong. want=%q, got=%q", tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package ast
+
+// 2つのノードを構造的に比較する
+// ノードの型、演算子、リテラルの値、子ノードを再帰的に比較し、トークンは比較しない
+func Equal(a, b Node) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	switch a := a.(type) {
+	case *Program:
+		b, ok := b.(*Program)
+		return ok && equalStatements(a.Statements, b.Statements)
+	case *LetStatement:
+		b, ok := b.(*LetStatement)
+		return ok && equalIdentifier(a.Name, b.Name) && equalExpression(a.Value, b.Value)
+	case *MultiLetStatement:
+		b, ok := b.(*MultiLetStatement)
+		return ok && equalIdentifiers(a.Names, b.Names) && equalExpressions(a.Values, b.Values)
+	case *ReturnStatement:
+		b, ok := b.(*ReturnStatement)
+		return ok && equalExpression(a.ReturnValue, b.ReturnValue)
+	case *WhileStatement:
+		b, ok := b.(*WhileStatement)
+		return ok && equalExpression(a.Condition, b.Condition) && equalBlock(a.Body, b.Body)
+	case *ForStatement:
+		b, ok := b.(*ForStatement)
+		return ok &&
+			equalStatement(a.Init, b.Init) &&
+			equalExpression(a.Condition, b.Condition) &&
+			equalStatement(a.Post, b.Post) &&
+			equalBlock(a.Body, b.Body)
+	case *BreakStatement:
+		_, ok := b.(*BreakStatement)
+		return ok
+	case *ContinueStatement:
+		_, ok := b.(*ContinueStatement)
+		return ok
+	case *BlockStatement:
+		b, ok := b.(*BlockStatement)
+		return ok && equalBlock(a, b)
+	case *ExpressionStatement:
+		b, ok := b.(*ExpressionStatement)
+		return ok && equalExpression(a.Expression, b.Expression)
+	case *Identifier:
+		b, ok := b.(*Identifier)
+		return ok && equalIdentifier(a, b)
+	case *IntegerLiteral:
+		b, ok := b.(*IntegerLiteral)
+		return ok && a.Value == b.Value
+	case *Boolean:
+		b, ok := b.(*Boolean)
+		return ok && a.Value == b.Value
+	case *NullLiteral:
+		_, ok := b.(*NullLiteral)
+		return ok
+	case *StringLiteral:
+		b, ok := b.(*StringLiteral)
+		return ok && a.Value == b.Value
+	case *PrefixExpression:
+		b, ok := b.(*PrefixExpression)
+		return ok && a.Operator == b.Operator && equalExpression(a.Right, b.Right)
+	case *InfixExpression:
+		b, ok := b.(*InfixExpression)
+		return ok && a.Operator == b.Operator &&
+			equalExpression(a.Left, b.Left) && equalExpression(a.Right, b.Right)
+	case *ComparisonChain:
+		b, ok := b.(*ComparisonChain)
+		return ok && equalStrings(a.Operators, b.Operators) && equalExpressions(a.Operands, b.Operands)
+	case *PostfixExpression:
+		b, ok := b.(*PostfixExpression)
+		return ok && a.Operator == b.Operator && equalExpression(a.Left, b.Left)
+	case *SpreadExpression:
+		b, ok := b.(*SpreadExpression)
+		return ok && equalExpression(a.Value, b.Value)
+	case *AssignExpression:
+		b, ok := b.(*AssignExpression)
+		return ok && equalIdentifier(a.Name, b.Name) && equalExpression(a.Value, b.Value)
+	case *TernaryExpression:
+		b, ok := b.(*TernaryExpression)
+		return ok &&
+			equalExpression(a.Condition, b.Condition) &&
+			equalExpression(a.Consequence, b.Consequence) &&
+			equalExpression(a.Alternative, b.Alternative)
+	case *IfExpression:
+		b, ok := b.(*IfExpression)
+		return ok &&
+			equalExpression(a.Condition, b.Condition) &&
+			equalBlock(a.Consequence, b.Consequence) &&
+			equalBlock(a.Alternative, b.Alternative)
+	case *BlockExpression:
+		b, ok := b.(*BlockExpression)
+		return ok && equalBlock(a.Block, b.Block)
+	case *FunctionLiteral:
+		b, ok := b.(*FunctionLiteral)
+		return ok && a.Name == b.Name && equalIdentifiers(a.Parameters, b.Parameters) && equalBlock(a.Body, b.Body)
+	case *MacroLiteral:
+		b, ok := b.(*MacroLiteral)
+		return ok && equalIdentifiers(a.Parameters, b.Parameters) && equalBlock(a.Body, b.Body)
+	case *CallExpression:
+		b, ok := b.(*CallExpression)
+		return ok && equalExpression(a.Function, b.Function) &&
+			equalExpressions(a.Arguments, b.Arguments)
+	case *ArrayLiteral:
+		b, ok := b.(*ArrayLiteral)
+		return ok && equalExpressions(a.Elements, b.Elements)
+	case *IndexExpression:
+		b, ok := b.(*IndexExpression)
+		return ok && equalExpression(a.Left, b.Left) && equalExpression(a.Index, b.Index)
+	case *HashLiteral:
+		b, ok := b.(*HashLiteral)
+		if !ok || len(a.Pairs) != len(b.Pairs) {
+			return false
+		}
+		for i, pair := range a.Pairs {
+			if !equalExpression(pair.Key, b.Pairs[i].Key) || !equalExpression(pair.Value, b.Pairs[i].Value) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// 以下はフィールドの型ごとの比較ヘルパー
+// 未設定(nil)のフィールドどうしは等しく、片方だけnilなら等しくない
+// *Identifier(nil) などをExpressionに変換すると非nilになるため、変換前に判定する
+
+func equalStatement(a, b Statement) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return Equal(a, b)
+}
+
+func equalExpression(a, b Expression) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return Equal(a, b)
+}
+
+func equalIdentifier(a, b *Identifier) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Value == b.Value
+}
+
+func equalBlock(a, b *BlockStatement) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return equalStatements(a.Statements, b.Statements)
+}
+
+func equalStatements(a, b []Statement) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalStatement(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalExpressions(a, b []Expression) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalExpression(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalIdentifiers(a, b []*Identifier) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !equalIdentifier(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
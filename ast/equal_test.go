@@ -0,0 +1,122 @@
+package ast
+
+import (
+	"testing"
+
+	"github.com/tamurayoshiya/monkey/token"
+)
+
+func TestEqual(t *testing.T) {
+	// let x = 1 + y;
+	newProgram := func() *Program {
+		return &Program{
+			Statements: []Statement{
+				&LetStatement{
+					Name: &Identifier{Value: "x"},
+					Value: &InfixExpression{
+						Left:     &IntegerLiteral{Value: 1},
+						Operator: "+",
+						Right:    &Identifier{Value: "y"},
+					},
+				},
+			},
+		}
+	}
+
+	if !Equal(newProgram(), newProgram()) {
+		t.Errorf("identical trees should be equal")
+	}
+
+	changed := newProgram()
+	changed.Statements[0].(*LetStatement).Value.(*InfixExpression).Operator = "-"
+	if Equal(newProgram(), changed) {
+		t.Errorf("trees with different operators should not be equal")
+	}
+
+	if Equal(newProgram(), &Program{}) {
+		t.Errorf("trees with different statement counts should not be equal")
+	}
+}
+
+func TestEqualMultiLetStatement(t *testing.T) {
+	// let a, b = 1, x;
+	newStatement := func() *MultiLetStatement {
+		return &MultiLetStatement{
+			Names:  []*Identifier{Ident("a"), Ident("b")},
+			Values: []Expression{Int(1), Ident("x")},
+		}
+	}
+
+	if !Equal(newStatement(), newStatement()) {
+		t.Errorf("identical statements should be equal")
+	}
+
+	swapped := newStatement()
+	swapped.Names[0], swapped.Names[1] = swapped.Names[1], swapped.Names[0]
+	if Equal(newStatement(), swapped) {
+		t.Errorf("statements binding different names should not be equal")
+	}
+
+	// let a = 1, b = x; は2つのLetStatementなので等しくない
+	if Equal(newStatement(), Let("a", Int(1))) {
+		t.Errorf("a multi-name let should not equal a single let")
+	}
+
+	if got := PrettyString(newStatement()); got != "let a, b = 1, x;" {
+		t.Errorf("PrettyString wrong. got=%q", got)
+	}
+}
+
+func TestEqualDistinguishesSameString(t *testing.T) {
+	tests := []struct {
+		a Node
+		b Node
+	}{
+		// どちらも String() は "1"
+		{
+			&IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1},
+			&Identifier{Value: "1"},
+		},
+		// どちらも String() は "(a[0])"
+		{
+			&IndexExpression{Left: &Identifier{Value: "a"}, Index: &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "0"}, Value: 0}},
+			&IndexExpression{Left: &Identifier{Value: "a"}, Index: &Identifier{Value: "0"}},
+		},
+		// どちらも String() は "true"
+		{
+			&Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true},
+			&Identifier{Value: "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.a.String() != tt.b.String() {
+			t.Fatalf("test setup: String() differs. %q != %q", tt.a.String(), tt.b.String())
+		}
+		if Equal(tt.a, tt.b) {
+			t.Errorf("%T and %T with String() %q should not be equal", tt.a, tt.b, tt.a.String())
+		}
+	}
+}
+
+func TestEqualNilFields(t *testing.T) {
+	withElse := &IfExpression{
+		Condition:   &Boolean{Value: true},
+		Consequence: &BlockStatement{},
+		Alternative: &BlockStatement{},
+	}
+	withoutElse := &IfExpression{
+		Condition:   &Boolean{Value: true},
+		Consequence: &BlockStatement{},
+	}
+
+	if Equal(withElse, withoutElse) {
+		t.Errorf("if with and without else should not be equal")
+	}
+	if !Equal(withoutElse, withoutElse) {
+		t.Errorf("if without else should equal itself")
+	}
+	if !Equal(&ForStatement{Body: &BlockStatement{}}, &ForStatement{Body: &BlockStatement{}}) {
+		t.Errorf("empty for statements should be equal")
+	}
+}
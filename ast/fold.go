@@ -0,0 +1,79 @@
+package ast
+
+import (
+	"strconv"
+
+	"github.com/tamurayoshiya/monkey/token"
+)
+
+// 両辺が整数リテラルの中置式 (+ - * /) を、計算結果の整数リテラルに置き換える
+// Modifyで子ノードから順に畳み込むので、2 * 3 + 1 は 7 になる
+// ゼロ除算は実行時のエラーにするため、畳み込まずにそのまま残す
+func FoldConstants(node Node) Node {
+	return Modify(node, foldInfix)
+}
+
+// 式がコンパイル時に値の決まる定数かどうか
+// 整数・真偽値・文字列・nullのリテラルと、定数だけを組み合わせた前置式・中置式が定数になる
+// 型が合わず実行時エラーになる式("a" - 1 など)も、入力が定数なので定数として扱う
+// 配列やハッシュのリテラルは、要素がすべて定数でも定数としない
+// 評価のたびに新しいオブジェクトを作るので、1つの値に置き換えられないため
+func IsConstant(e Expression) bool {
+	switch e := e.(type) {
+	case *IntegerLiteral, *Boolean, *StringLiteral, *NullLiteral:
+		return true
+	case *PrefixExpression:
+		return IsConstant(e.Right)
+	case *InfixExpression:
+		return IsConstant(e.Left) && IsConstant(e.Right)
+	case *ComparisonChain:
+		for _, operand := range e.Operands {
+			if !IsConstant(operand) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+func foldInfix(node Node) Node {
+	infix, ok := node.(*InfixExpression)
+	if !ok {
+		return node
+	}
+	left, ok := infix.Left.(*IntegerLiteral)
+	if !ok {
+		return node
+	}
+	right, ok := infix.Right.(*IntegerLiteral)
+	if !ok {
+		return node
+	}
+
+	var value int64
+	switch infix.Operator {
+	case "+":
+		value = left.Value + right.Value
+	case "-":
+		value = left.Value - right.Value
+	case "*":
+		value = left.Value * right.Value
+	case "/":
+		if right.Value == 0 {
+			return node
+		}
+		value = left.Value / right.Value
+	default:
+		return node
+	}
+
+	tok := token.Token{
+		Type:    token.INT,
+		Literal: strconv.FormatInt(value, 10),
+		Line:    left.Token.Line,
+		Column:  left.Token.Column,
+	}
+	return &IntegerLiteral{Token: tok, Value: value}
+}
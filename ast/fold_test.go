@@ -0,0 +1,111 @@
+package ast
+
+import "testing"
+
+func TestFoldConstants(t *testing.T) {
+	integer := func(value int64) *IntegerLiteral { return &IntegerLiteral{Value: value} }
+	infix := func(left Expression, operator string, right Expression) *InfixExpression {
+		return &InfixExpression{Left: left, Operator: operator, Right: right}
+	}
+	x := &Identifier{Value: "x"}
+	f := &Identifier{Value: "f"}
+
+	tests := []struct {
+		input    Expression
+		expected Expression
+	}{
+		{infix(integer(1), "+", integer(2)), integer(3)},
+		{infix(integer(1), "-", integer(2)), integer(-1)},
+		{infix(integer(7), "/", integer(2)), integer(3)},
+		// 2 * 3 + 1
+		{infix(infix(integer(2), "*", integer(3)), "+", integer(1)), integer(7)},
+		// (1 + 2) * (3 - 4)
+		{
+			infix(infix(integer(1), "+", integer(2)), "*", infix(integer(3), "-", integer(4))),
+			integer(-3),
+		},
+		// x + 2 * 3 は右辺だけ畳み込む
+		{infix(x, "+", infix(integer(2), "*", integer(3))), infix(x, "+", integer(6))},
+		// 比較演算子は畳み込まない
+		{infix(integer(1), "<", integer(2)), infix(integer(1), "<", integer(2))},
+		// ゼロ除算は畳み込まない
+		{infix(integer(1), "/", integer(0)), infix(integer(1), "/", integer(0))},
+		{
+			infix(infix(integer(1), "+", integer(1)), "/", infix(integer(2), "-", integer(2))),
+			infix(integer(2), "/", integer(0)),
+		},
+		// 呼び出しの引数も畳み込む: f(1 + 2) は f(3)
+		{
+			&CallExpression{Function: f, Arguments: []Expression{infix(integer(1), "+", integer(2))}},
+			&CallExpression{Function: f, Arguments: []Expression{integer(3)}},
+		},
+	}
+
+	for _, tt := range tests {
+		program := &Program{
+			Statements: []Statement{&ExpressionStatement{Expression: tt.input}},
+		}
+		expected := &Program{
+			Statements: []Statement{&ExpressionStatement{Expression: tt.expected}},
+		}
+
+		folded := FoldConstants(program)
+		if !Equal(folded, expected) {
+			t.Errorf("wrong folding. want=%s, got=%s", PrettyString(expected), PrettyString(folded))
+		}
+	}
+}
+
+func TestFoldConstantsTokenLiteral(t *testing.T) {
+	exp := &InfixExpression{
+		Left:     &IntegerLiteral{Value: 2},
+		Operator: "*",
+		Right:    &IntegerLiteral{Value: 21},
+	}
+
+	node := FoldConstants(exp)
+	folded, ok := node.(*IntegerLiteral)
+	if !ok {
+		t.Fatalf("folded node is not *IntegerLiteral. got=%T", node)
+	}
+	if folded.Value != 42 {
+		t.Errorf("folded.Value wrong. want=42, got=%d", folded.Value)
+	}
+	if folded.String() != "42" {
+		t.Errorf("folded.String() wrong. want=%q, got=%q", "42", folded.String())
+	}
+}
+
+func TestIsConstant(t *testing.T) {
+	integer := func(value int64) *IntegerLiteral { return &IntegerLiteral{Value: value} }
+	infix := func(left Expression, operator string, right Expression) *InfixExpression {
+		return &InfixExpression{Left: left, Operator: operator, Right: right}
+	}
+	x := &Identifier{Value: "x"}
+
+	tests := []struct {
+		input    Expression
+		expected bool
+	}{
+		{integer(5), true},
+		{&Boolean{Value: true}, true},
+		{&StringLiteral{Value: "a"}, true},
+		{&NullLiteral{}, true},
+		{infix(integer(2), "+", integer(3)), true},
+		{&PrefixExpression{Operator: "-", Right: infix(integer(2), "*", integer(3))}, true},
+		{infix(&StringLiteral{Value: "a"}, "-", integer(1)), true},
+		{x, false},
+		{infix(x, "+", integer(1)), false},
+		{&PrefixExpression{Operator: "!", Right: x}, false},
+		// 配列は要素が定数でも定数としない
+		{&ArrayLiteral{Elements: []Expression{integer(1), integer(2)}}, false},
+		{&CallExpression{Function: x, Arguments: []Expression{integer(1)}}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := IsConstant(tt.input); got != tt.expected {
+			t.Errorf("IsConstant(%v) wrong. want=%t, got=%t", tt.input, tt.expected, got)
+		}
+	}
+}
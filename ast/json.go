@@ -0,0 +1,191 @@
+package ast
+
+import (
+	"encoding/json"
+)
+
+// 構文木をJSONとして出力する(エディタなどのツール向け)
+// 各ノードは "type" にノードの型名を持ち、フィールドはキャメルケースのキーで出力される
+func (p *Program) MarshalJSON() ([]byte, error) {
+	return json.Marshal(nodeToJSON(p))
+}
+
+// ノードをJSONに変換できる値に変換する。nilのノードはnullになる
+func nodeToJSON(node Node) interface{} {
+	if node == nil {
+		return nil
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		return jsonNode("Program", "statements", statementsToJSON(node.Statements))
+	case *LetStatement:
+		return jsonNode("LetStatement",
+			"name", identifierToJSON(node.Name),
+			"value", expressionToJSON(node.Value))
+	case *MultiLetStatement:
+		return jsonNode("MultiLetStatement",
+			"names", identifiersToJSON(node.Names),
+			"values", expressionsToJSON(node.Values))
+	case *ReturnStatement:
+		return jsonNode("ReturnStatement", "returnValue", expressionToJSON(node.ReturnValue))
+	case *WhileStatement:
+		return jsonNode("WhileStatement",
+			"condition", expressionToJSON(node.Condition),
+			"body", blockToJSON(node.Body))
+	case *ForStatement:
+		return jsonNode("ForStatement",
+			"init", statementToJSON(node.Init),
+			"condition", expressionToJSON(node.Condition),
+			"post", statementToJSON(node.Post),
+			"body", blockToJSON(node.Body))
+	case *BreakStatement:
+		return jsonNode("BreakStatement")
+	case *ContinueStatement:
+		return jsonNode("ContinueStatement")
+	case *BlockStatement:
+		return blockToJSON(node)
+	case *ExpressionStatement:
+		return jsonNode("ExpressionStatement", "expression", expressionToJSON(node.Expression))
+	case *Identifier:
+		return identifierToJSON(node)
+	case *IntegerLiteral:
+		return jsonNode("IntegerLiteral", "value", node.Value)
+	case *Boolean:
+		return jsonNode("Boolean", "value", node.Value)
+	case *NullLiteral:
+		return jsonNode("NullLiteral")
+	case *StringLiteral:
+		return jsonNode("StringLiteral", "value", node.Value)
+	case *PrefixExpression:
+		return jsonNode("PrefixExpression",
+			"operator", node.Operator,
+			"right", expressionToJSON(node.Right))
+	case *InfixExpression:
+		return jsonNode("InfixExpression",
+			"left", expressionToJSON(node.Left),
+			"operator", node.Operator,
+			"right", expressionToJSON(node.Right))
+	case *ComparisonChain:
+		return jsonNode("ComparisonChain",
+			"operands", expressionsToJSON(node.Operands),
+			"operators", node.Operators)
+	case *PostfixExpression:
+		return jsonNode("PostfixExpression",
+			"left", expressionToJSON(node.Left),
+			"operator", node.Operator)
+	case *SpreadExpression:
+		return jsonNode("SpreadExpression", "value", expressionToJSON(node.Value))
+	case *AssignExpression:
+		return jsonNode("AssignExpression",
+			"name", identifierToJSON(node.Name),
+			"value", expressionToJSON(node.Value))
+	case *TernaryExpression:
+		return jsonNode("TernaryExpression",
+			"condition", expressionToJSON(node.Condition),
+			"consequence", expressionToJSON(node.Consequence),
+			"alternative", expressionToJSON(node.Alternative))
+	case *IfExpression:
+		return jsonNode("IfExpression",
+			"condition", expressionToJSON(node.Condition),
+			"consequence", blockToJSON(node.Consequence),
+			"alternative", blockToJSON(node.Alternative))
+	case *BlockExpression:
+		return jsonNode("BlockExpression",
+			"block", blockToJSON(node.Block))
+	case *FunctionLiteral:
+		return jsonNode("FunctionLiteral",
+			"name", node.Name,
+			"parameters", identifiersToJSON(node.Parameters),
+			"body", blockToJSON(node.Body))
+	case *MacroLiteral:
+		return jsonNode("MacroLiteral",
+			"parameters", identifiersToJSON(node.Parameters),
+			"body", blockToJSON(node.Body))
+	case *CallExpression:
+		return jsonNode("CallExpression",
+			"function", expressionToJSON(node.Function),
+			"arguments", expressionsToJSON(node.Arguments))
+	case *ArrayLiteral:
+		return jsonNode("ArrayLiteral", "elements", expressionsToJSON(node.Elements))
+	case *IndexExpression:
+		return jsonNode("IndexExpression",
+			"left", expressionToJSON(node.Left),
+			"index", expressionToJSON(node.Index))
+	case *HashLiteral:
+		// ペアはソース上の順序で配列として出力する
+		pairs := make([]interface{}, 0, len(node.Pairs))
+		for _, pair := range node.Pairs {
+			pairs = append(pairs, map[string]interface{}{
+				"key":   expressionToJSON(pair.Key),
+				"value": expressionToJSON(pair.Value),
+			})
+		}
+		return jsonNode("HashLiteral", "pairs", pairs)
+	}
+	return nil
+}
+
+// "type" と、キーと値を交互に並べたフィールドからJSONオブジェクトを作る
+func jsonNode(typeName string, fields ...interface{}) map[string]interface{} {
+	obj := map[string]interface{}{"type": typeName}
+	for i := 0; i+1 < len(fields); i += 2 {
+		obj[fields[i].(string)] = fields[i+1]
+	}
+	return obj
+}
+
+// 以下はフィールドをJSONの値に変換するヘルパー
+// 未設定(nil)のフィールドは、型付きのnilのままnodeToJSONに渡さずnullとして出力する
+
+func statementToJSON(s Statement) interface{} {
+	if s == nil {
+		return nil
+	}
+	return nodeToJSON(s)
+}
+
+func expressionToJSON(e Expression) interface{} {
+	if e == nil {
+		return nil
+	}
+	return nodeToJSON(e)
+}
+
+func identifierToJSON(i *Identifier) interface{} {
+	if i == nil {
+		return nil
+	}
+	return jsonNode("Identifier", "value", i.Value)
+}
+
+func blockToJSON(b *BlockStatement) interface{} {
+	if b == nil {
+		return nil
+	}
+	return jsonNode("BlockStatement", "statements", statementsToJSON(b.Statements))
+}
+
+func statementsToJSON(statements []Statement) []interface{} {
+	out := make([]interface{}, 0, len(statements))
+	for _, s := range statements {
+		out = append(out, statementToJSON(s))
+	}
+	return out
+}
+
+func expressionsToJSON(expressions []Expression) []interface{} {
+	out := make([]interface{}, 0, len(expressions))
+	for _, e := range expressions {
+		out = append(out, expressionToJSON(e))
+	}
+	return out
+}
+
+func identifiersToJSON(identifiers []*Identifier) []interface{} {
+	out := make([]interface{}, 0, len(identifiers))
+	for _, i := range identifiers {
+		out = append(out, identifierToJSON(i))
+	}
+	return out
+}
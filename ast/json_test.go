@@ -0,0 +1,84 @@
+package ast
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tamurayoshiya/monkey/token"
+)
+
+func TestProgramMarshalJSON(t *testing.T) {
+	// let x = 5;
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let"},
+				Name: &Identifier{
+					Token: token.Token{Type: token.IDENT, Literal: "x"},
+					Value: "x",
+				},
+				Value: &IntegerLiteral{
+					Token: token.Token{Type: token.INT, Literal: "5"},
+					Value: 5,
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %s", err)
+	}
+
+	expected := `{"statements":[{"name":{"type":"Identifier","value":"x"},"type":"LetStatement","value":{"type":"IntegerLiteral","value":5}}],"type":"Program"}`
+	if string(data) != expected {
+		t.Fatalf("wrong JSON.\nwant=%s\ngot=%s", expected, data)
+	}
+
+	// 各階層に "type" があること、整数は数値として出力されることを確認する
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal returned error: %s", err)
+	}
+	if decoded["type"] != "Program" {
+		t.Errorf("program type wrong. got=%v", decoded["type"])
+	}
+	let := decoded["statements"].([]interface{})[0].(map[string]interface{})
+	if let["type"] != "LetStatement" {
+		t.Errorf("statement type wrong. got=%v", let["type"])
+	}
+	name := let["name"].(map[string]interface{})
+	if name["type"] != "Identifier" || name["value"] != "x" {
+		t.Errorf("name wrong. got=%v", name)
+	}
+	value := let["value"].(map[string]interface{})
+	if value["type"] != "IntegerLiteral" || value["value"] != float64(5) {
+		t.Errorf("value wrong. got=%v", value)
+	}
+}
+
+func TestMarshalJSONLiteralValues(t *testing.T) {
+	program := &Program{
+		Statements: []Statement{
+			&ExpressionStatement{Expression: &StringLiteral{Value: "5"}},
+			&ExpressionStatement{Expression: &IfExpression{
+				Condition:   &Boolean{Value: true},
+				Consequence: &BlockStatement{},
+			}},
+		},
+	}
+
+	data, err := json.Marshal(program)
+	if err != nil {
+		t.Fatalf("json.Marshal returned error: %s", err)
+	}
+
+	expected := `{"statements":[` +
+		`{"expression":{"type":"StringLiteral","value":"5"},"type":"ExpressionStatement"},` +
+		`{"expression":{"alternative":null,"condition":{"type":"Boolean","value":true},` +
+		`"consequence":{"statements":[],"type":"BlockStatement"},"type":"IfExpression"},"type":"ExpressionStatement"}` +
+		`],"type":"Program"}`
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nwant=%s\ngot=%s", expected, data)
+	}
+}
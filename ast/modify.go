@@ -13,17 +13,52 @@ func Modify(node Node, modifier ModifierFunc) Node {
 	case *InfixExpression:
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
+	case *ComparisonChain:
+		for i, _ := range node.Operands {
+			node.Operands[i], _ = Modify(node.Operands[i], modifier).(Expression)
+		}
+	case *TernaryExpression:
+		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		node.Consequence, _ = Modify(node.Consequence, modifier).(Expression)
+		node.Alternative, _ = Modify(node.Alternative, modifier).(Expression)
+	case *PostfixExpression:
+		node.Left, _ = Modify(node.Left, modifier).(Expression)
+	case *SpreadExpression:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *AssignExpression:
+		node.Value, _ = Modify(node.Value, modifier).(Expression)
 	case *PrefixExpression:
 		node.Right, _ = Modify(node.Right, modifier).(Expression)
 	case *IndexExpression:
 		node.Left, _ = Modify(node.Left, modifier).(Expression)
 		node.Index, _ = Modify(node.Index, modifier).(Expression)
+	case *CallExpression:
+		node.Function, _ = Modify(node.Function, modifier).(Expression)
+		for i, _ := range node.Arguments {
+			node.Arguments[i], _ = Modify(node.Arguments[i], modifier).(Expression)
+		}
 	case *IfExpression:
 		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
 		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
 		if node.Alternative != nil {
 			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
 		}
+	case *BlockExpression:
+		node.Block, _ = Modify(node.Block, modifier).(*BlockStatement)
+	case *WhileStatement:
+		node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *ForStatement:
+		if node.Init != nil {
+			node.Init, _ = Modify(node.Init, modifier).(Statement)
+		}
+		if node.Condition != nil {
+			node.Condition, _ = Modify(node.Condition, modifier).(Expression)
+		}
+		if node.Post != nil {
+			node.Post, _ = Modify(node.Post, modifier).(Statement)
+		}
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
 	case *BlockStatement:
 		for i, _ := range node.Statements {
 			node.Statements[i], _ = Modify(node.Statements[i], modifier).(Statement)
@@ -32,6 +67,10 @@ func Modify(node Node, modifier ModifierFunc) Node {
 		node.ReturnValue, _ = Modify(node.ReturnValue, modifier).(Expression)
 	case *LetStatement:
 		node.Value, _ = Modify(node.Value, modifier).(Expression)
+	case *MultiLetStatement:
+		for i, _ := range node.Values {
+			node.Values[i], _ = Modify(node.Values[i], modifier).(Expression)
+		}
 	case *FunctionLiteral:
 		for i, _ := range node.Parameters {
 			node.Parameters[i], _ = Modify(node.Parameters[i], modifier).(*Identifier)
@@ -42,13 +81,10 @@ func Modify(node Node, modifier ModifierFunc) Node {
 			node.Elements[i], _ = Modify(node.Elements[i], modifier).(Expression)
 		}
 	case *HashLiteral:
-		newPairs := make(map[Expression]Expression)
-		for key, val := range node.Pairs {
-			newKey, _ := Modify(key, modifier).(Expression)
-			newVal, _ := Modify(val, modifier).(Expression)
-			newPairs[newKey] = newVal
+		for i, pair := range node.Pairs {
+			node.Pairs[i].Key, _ = Modify(pair.Key, modifier).(Expression)
+			node.Pairs[i].Value, _ = Modify(pair.Value, modifier).(Expression)
 		}
-		node.Pairs = newPairs
 	}
 	return modifier(node)
 }
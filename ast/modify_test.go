@@ -1,8 +1,11 @@
 package ast
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/tamurayoshiya/monkey/token"
 )
 
 func TestModify(t *testing.T) {
@@ -29,6 +32,10 @@ func TestModify(t *testing.T) {
 			one(),
 			two(),
 		},
+		{
+			&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one(), one()}},
+			&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{two(), two()}},
+		},
 		{
 			&Program{
 				Statements: []Statement{
@@ -188,22 +195,61 @@ func TestModify(t *testing.T) {
 
 	// test for hash literal
 	hashLiteral := &HashLiteral{
-		Pairs: map[Expression]Expression{
-			one(): one(),
-			one(): one(),
+		Pairs: []HashPair{
+			{Key: one(), Value: one()},
+			{Key: one(), Value: one()},
 		},
 	}
 
 	Modify(hashLiteral, turnOneIntoTwo)
 
-	for key, val := range hashLiteral.Pairs {
-		key, _ := key.(*IntegerLiteral)
+	for _, pair := range hashLiteral.Pairs {
+		key, _ := pair.Key.(*IntegerLiteral)
 		if key.Value != 2 {
 			t.Errorf("value is not %d, got=%d", 2, key.Value)
 		}
-		val, _ := val.(*IntegerLiteral)
+		val, _ := pair.Value.(*IntegerLiteral)
 		if val.Value != 2 {
 			t.Errorf("value is not %d, got=%d", 2, val.Value)
 		}
 	}
 }
+
+func TestModifyDoublesIntegers(t *testing.T) {
+	integer := func(v int64) *IntegerLiteral {
+		return &IntegerLiteral{
+			Token: token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", v)},
+			Value: v,
+		}
+	}
+
+	// [1, 2 * 3]
+	input := &ArrayLiteral{
+		Token: token.Token{Type: token.LBRACKET, Literal: "["},
+		Elements: []Expression{
+			integer(1),
+			&InfixExpression{
+				Token:    token.Token{Type: token.ASTERISK, Literal: "*"},
+				Left:     integer(2),
+				Operator: "*",
+				Right:    integer(3),
+			},
+		},
+	}
+
+	// 子ノードから順に置き換えられるため、Stringにも新しいリテラルが反映される
+	double := func(node Node) Node {
+		il, ok := node.(*IntegerLiteral)
+		if !ok {
+			return node
+		}
+		return integer(il.Value * 2)
+	}
+
+	modified := Modify(input, double)
+
+	expected := "[2, (4 * 6)]"
+	if modified.String() != expected {
+		t.Errorf("modified.String() wrong. want=%q, got=%q", expected, modified.String())
+	}
+}
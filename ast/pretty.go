@@ -0,0 +1,245 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+)
+
+// 人が読むための整形出力。String()とは異なり、文ごとに改行し、ブロックをインデントする
+// if や関数の本体の波括弧はそれぞれ独立した行に置く
+func (p *Program) PrettyString() string {
+	return PrettyString(p)
+}
+
+// 任意のノードを整形して出力する
+func PrettyString(node Node) string {
+	pp := &prettyPrinter{}
+	switch node := node.(type) {
+	case *Program:
+		for _, s := range node.Statements {
+			pp.writeStatement(s)
+			pp.out.WriteString("\n")
+		}
+	case Statement:
+		pp.writeStatement(node)
+	case Expression:
+		pp.writeExpression(node)
+	}
+	return pp.out.String()
+}
+
+const prettyIndent = "    "
+
+type prettyPrinter struct {
+	out    bytes.Buffer
+	indent int
+}
+
+func (pp *prettyPrinter) writeIndent() {
+	pp.out.WriteString(strings.Repeat(prettyIndent, pp.indent))
+}
+
+// 文を出力する。行頭のインデントと末尾の改行は呼び出し側で書く
+func (pp *prettyPrinter) writeStatement(s Statement) {
+	switch s := s.(type) {
+	case *LetStatement:
+		pp.out.WriteString("let " + s.Name.String() + " = ")
+		pp.writeExpression(s.Value)
+		pp.out.WriteString(";")
+	case *MultiLetStatement:
+		names := []string{}
+		for _, n := range s.Names {
+			names = append(names, n.String())
+		}
+		pp.out.WriteString("let " + strings.Join(names, ", ") + " = ")
+		pp.writeExpressionList(s.Values)
+		pp.out.WriteString(";")
+	case *ReturnStatement:
+		pp.out.WriteString("return")
+		if s.ReturnValue != nil {
+			pp.out.WriteString(" ")
+			pp.writeExpression(s.ReturnValue)
+		}
+		pp.out.WriteString(";")
+	case *ExpressionStatement:
+		pp.writeExpression(s.Expression)
+		// ブロックで終わる式には ; を付けない
+		if _, ok := s.Expression.(*IfExpression); !ok {
+			pp.out.WriteString(";")
+		}
+	case *WhileStatement:
+		pp.out.WriteString("while ")
+		pp.writeCondition(s.Condition)
+		pp.writeBlock(s.Body)
+	case *ForStatement:
+		// 初期化文と更新文はブロックを含まないため String() を使う
+		init, cond, post := "", "", ""
+		if s.Init != nil {
+			init = strings.TrimSuffix(s.Init.String(), ";")
+		}
+		if s.Condition != nil {
+			cond = s.Condition.String()
+		}
+		if s.Post != nil {
+			post = strings.TrimSuffix(s.Post.String(), ";")
+		}
+		pp.out.WriteString("for (" + init + "; " + cond + "; " + post + ")")
+		pp.writeBlock(s.Body)
+	case *BreakStatement:
+		pp.out.WriteString("break;")
+	case *ContinueStatement:
+		pp.out.WriteString("continue;")
+	case *BlockStatement:
+		pp.writeBlock(s)
+	default:
+		pp.out.WriteString(s.String())
+	}
+}
+
+// 波括弧を独立した行に置いてブロックを出力する
+func (pp *prettyPrinter) writeBlock(b *BlockStatement) {
+	pp.out.WriteString("\n")
+	pp.writeIndent()
+	pp.writeBraces(b)
+}
+
+// ブロックの文を { } で囲んで出力する。ブロック式はこれを式の位置にそのまま出力する
+func (pp *prettyPrinter) writeBraces(b *BlockStatement) {
+	pp.out.WriteString("{\n")
+	pp.indent++
+	for _, s := range b.Statements {
+		pp.writeIndent()
+		pp.writeStatement(s)
+		pp.out.WriteString("\n")
+	}
+	pp.indent--
+	pp.writeIndent()
+	pp.out.WriteString("}")
+}
+
+// 条件式を括弧で囲んで出力する。既に括弧付きで出力される式はそのまま使う
+func (pp *prettyPrinter) writeCondition(cond Expression) {
+	s := cond.String()
+	if strings.HasPrefix(s, "(") {
+		pp.out.WriteString(s)
+		return
+	}
+	pp.out.WriteString("(" + s + ")")
+}
+
+// 式を出力する。ブロックを含みうる式は子を再帰的に整形し、それ以外は String() と同じ形になる
+// String()と同じく、二項演算子の式は外側の括弧を省いて書く
+func (pp *prettyPrinter) writeTernaryPart(e Expression) {
+	if !isBinaryOperation(e) {
+		pp.writeExpression(e)
+		return
+	}
+	part := &prettyPrinter{indent: pp.indent}
+	part.writeExpression(e)
+	s := part.out.String()
+	pp.out.WriteString(s[1 : len(s)-1])
+}
+
+func (pp *prettyPrinter) writeExpression(e Expression) {
+	switch e := e.(type) {
+	case *IfExpression:
+		pp.out.WriteString("if ")
+		pp.writeCondition(e.Condition)
+		pp.writeBlock(e.Consequence)
+		if e.Alternative != nil {
+			pp.out.WriteString("\n")
+			pp.writeIndent()
+			pp.out.WriteString("else")
+			pp.writeBlock(e.Alternative)
+		}
+	case *BlockExpression:
+		pp.writeBraces(e.Block)
+	case *FunctionLiteral:
+		pp.out.WriteString("fn")
+		pp.writeParameters(e.Parameters)
+		pp.writeBlock(e.Body)
+	case *MacroLiteral:
+		pp.out.WriteString("macro")
+		pp.writeParameters(e.Parameters)
+		pp.writeBlock(e.Body)
+	case *PrefixExpression:
+		pp.out.WriteString("(" + e.Operator)
+		pp.writeExpression(e.Right)
+		pp.out.WriteString(")")
+	case *InfixExpression:
+		pp.out.WriteString("(")
+		pp.writeExpression(e.Left)
+		pp.out.WriteString(" " + e.Operator + " ")
+		pp.writeExpression(e.Right)
+		pp.out.WriteString(")")
+	case *ComparisonChain:
+		pp.out.WriteString("(")
+		for i, operand := range e.Operands {
+			if i > 0 {
+				pp.out.WriteString(" " + e.Operators[i-1] + " ")
+			}
+			pp.writeExpression(operand)
+		}
+		pp.out.WriteString(")")
+	case *SpreadExpression:
+		pp.out.WriteString("...")
+		pp.writeExpression(e.Value)
+	case *AssignExpression:
+		pp.out.WriteString("(" + e.Name.String() + " = ")
+		pp.writeExpression(e.Value)
+		pp.out.WriteString(")")
+	case *TernaryExpression:
+		pp.out.WriteString("(")
+		pp.writeTernaryPart(e.Condition)
+		pp.out.WriteString(" ? ")
+		pp.writeTernaryPart(e.Consequence)
+		pp.out.WriteString(" : ")
+		pp.writeTernaryPart(e.Alternative)
+		pp.out.WriteString(")")
+	case *CallExpression:
+		pp.writeExpression(e.Function)
+		pp.out.WriteString("(")
+		pp.writeExpressionList(e.Arguments)
+		pp.out.WriteString(")")
+	case *IndexExpression:
+		pp.out.WriteString("(")
+		pp.writeExpression(e.Left)
+		pp.out.WriteString("[")
+		pp.writeExpression(e.Index)
+		pp.out.WriteString("])")
+	case *ArrayLiteral:
+		pp.out.WriteString("[")
+		pp.writeExpressionList(e.Elements)
+		pp.out.WriteString("]")
+	case *HashLiteral:
+		pp.out.WriteString("{")
+		for i, pair := range e.Pairs {
+			if i > 0 {
+				pp.out.WriteString(", ")
+			}
+			pp.writeExpression(pair.Key)
+			pp.out.WriteString(": ")
+			pp.writeExpression(pair.Value)
+		}
+		pp.out.WriteString("}")
+	default:
+		pp.out.WriteString(e.String())
+	}
+}
+
+func (pp *prettyPrinter) writeExpressionList(list []Expression) {
+	for i, e := range list {
+		if i > 0 {
+			pp.out.WriteString(", ")
+		}
+		pp.writeExpression(e)
+	}
+}
+
+func (pp *prettyPrinter) writeParameters(params []*Identifier) {
+	names := []string{}
+	for _, p := range params {
+		names = append(names, p.String())
+	}
+	pp.out.WriteString("(" + strings.Join(names, ", ") + ")")
+}
@@ -0,0 +1,118 @@
+package ast
+
+import (
+	"testing"
+)
+
+func TestPrettyString(t *testing.T) {
+	// let max = fn(a, b) { if (a > b) { return a; } else { return b; } };
+	// max(1, 2);
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "max"},
+				Value: &FunctionLiteral{
+					Parameters: []*Identifier{{Value: "a"}, {Value: "b"}},
+					Body: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{
+								Expression: &IfExpression{
+									Condition: &InfixExpression{
+										Left:     &Identifier{Value: "a"},
+										Operator: ">",
+										Right:    &Identifier{Value: "b"},
+									},
+									Consequence: &BlockStatement{
+										Statements: []Statement{
+											&ReturnStatement{ReturnValue: &Identifier{Value: "a"}},
+										},
+									},
+									Alternative: &BlockStatement{
+										Statements: []Statement{
+											&ReturnStatement{ReturnValue: &Identifier{Value: "b"}},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			&ExpressionStatement{
+				Expression: &CallExpression{
+					Function:  &Identifier{Value: "max"},
+					Arguments: []Expression{&Identifier{Value: "x"}, &Identifier{Value: "y"}},
+				},
+			},
+		},
+	}
+
+	expected := `let max = fn(a, b)
+{
+    if (a > b)
+    {
+        return a;
+    }
+    else
+    {
+        return b;
+    }
+};
+max(x, y);
+`
+	if program.PrettyString() != expected {
+		t.Errorf("PrettyString wrong.\nwant:\n%s\ngot:\n%s", expected, program.PrettyString())
+	}
+}
+
+func TestPrettyStringLoops(t *testing.T) {
+	// while (x) { x = f(x); }
+	node := &WhileStatement{
+		Condition: &Identifier{Value: "x"},
+		Body: &BlockStatement{
+			Statements: []Statement{
+				&ExpressionStatement{
+					Expression: &AssignExpression{
+						Name: &Identifier{Value: "x"},
+						Value: &CallExpression{
+							Function:  &Identifier{Value: "f"},
+							Arguments: []Expression{&Identifier{Value: "x"}},
+						},
+					},
+				},
+				&BreakStatement{},
+			},
+		},
+	}
+
+	expected := `while (x)
+{
+    (x = f(x));
+    break;
+}`
+	if PrettyString(node) != expected {
+		t.Errorf("PrettyString wrong.\nwant:\n%s\ngot:\n%s", expected, PrettyString(node))
+	}
+}
+
+func TestPrettyStringTernary(t *testing.T) {
+	// a > b ? a : b
+	node := &TernaryExpression{
+		Condition: &InfixExpression{
+			Operator: ">",
+			Left:     &Identifier{Value: "a"},
+			Right:    &Identifier{Value: "b"},
+		},
+		Consequence: &Identifier{Value: "a"},
+		Alternative: &Identifier{Value: "b"},
+	}
+
+	// String()と同じく、条件の二項演算子の括弧は省く
+	expected := "(a > b ? a : b)"
+	if PrettyString(node) != expected {
+		t.Errorf("PrettyString wrong. want=%q, got=%q", expected, PrettyString(node))
+	}
+	if node.String() != expected {
+		t.Errorf("String wrong. want=%q, got=%q", expected, node.String())
+	}
+}
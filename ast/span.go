@@ -0,0 +1,218 @@
+package ast
+
+import "github.com/tamurayoshiya/monkey/token"
+
+// ソース上の位置。行と列は1始まりで、ゼロ値は位置が不明なことを表す
+// (パーサー以外で生成されたノードなど)
+type Position struct {
+	Line   int
+	Column int
+}
+
+// 位置が分かっているかどうか
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+func positionOf(tok token.Token) Position {
+	return Position{Line: tok.Line, Column: tok.Column}
+}
+
+// 省略可能なトークン(';'など)があればその位置を、なければfallbackを返す
+func positionOr(tok token.Token, fallback Position) Position {
+	if tok.Line > 0 {
+		return positionOf(tok)
+	}
+	return fallback
+}
+
+// nilのノードはゼロ値の位置とする
+func startOf(n Node) Position {
+	if n == nil {
+		return Position{}
+	}
+	return n.Start()
+}
+
+func endOf(n Node) Position {
+	if n == nil {
+		return Position{}
+	}
+	return n.End()
+}
+
+// -----------------------------------------------------
+
+// Start と End は、ノードの最初と最後のトークンの位置を返す
+// `let x = 5;` なら Start は'let'の位置、End は';'の位置になる
+// 括弧 `(` `)` はノードとして残らないので、(1 + 2) の範囲は 1 から 2 まで
+
+func (p *Program) Start() Position {
+	if len(p.Statements) == 0 {
+		return Position{}
+	}
+	return p.Statements[0].Start()
+}
+func (p *Program) End() Position {
+	if len(p.Statements) == 0 {
+		return Position{}
+	}
+	return p.Statements[len(p.Statements)-1].End()
+}
+
+func (i *Identifier) Start() Position {
+	if i == nil {
+		return Position{}
+	}
+	return positionOf(i.Token)
+}
+func (i *Identifier) End() Position {
+	return i.Start()
+}
+
+func (il *IntegerLiteral) Start() Position { return positionOf(il.Token) }
+func (il *IntegerLiteral) End() Position   { return positionOf(il.Token) }
+
+func (b *Boolean) Start() Position { return positionOf(b.Token) }
+func (b *Boolean) End() Position   { return positionOf(b.Token) }
+
+func (n *NullLiteral) Start() Position { return positionOf(n.Token) }
+func (n *NullLiteral) End() Position   { return positionOf(n.Token) }
+
+func (sl *StringLiteral) Start() Position { return positionOf(sl.Token) }
+func (sl *StringLiteral) End() Position   { return positionOf(sl.Token) }
+
+func (ls *LetStatement) Start() Position { return positionOf(ls.Token) }
+func (ls *LetStatement) End() Position {
+	return positionOr(ls.Semicolon, endOf(ls.Value))
+}
+
+func (ms *MultiLetStatement) Start() Position { return positionOf(ms.Token) }
+func (ms *MultiLetStatement) End() Position {
+	if len(ms.Values) == 0 {
+		return positionOr(ms.Semicolon, positionOf(ms.Token))
+	}
+	return positionOr(ms.Semicolon, endOf(ms.Values[len(ms.Values)-1]))
+}
+
+func (rs *ReturnStatement) Start() Position { return positionOf(rs.Token) }
+func (rs *ReturnStatement) End() Position {
+	if rs.ReturnValue == nil {
+		return positionOr(rs.Semicolon, positionOf(rs.Token))
+	}
+	return positionOr(rs.Semicolon, rs.ReturnValue.End())
+}
+
+func (ws *WhileStatement) Start() Position { return positionOf(ws.Token) }
+func (ws *WhileStatement) End() Position   { return ws.Body.End() }
+
+func (fs *ForStatement) Start() Position { return positionOf(fs.Token) }
+func (fs *ForStatement) End() Position   { return fs.Body.End() }
+
+func (bs *BreakStatement) Start() Position { return positionOf(bs.Token) }
+func (bs *BreakStatement) End() Position {
+	return positionOr(bs.Semicolon, positionOf(bs.Token))
+}
+
+func (cs *ContinueStatement) Start() Position { return positionOf(cs.Token) }
+func (cs *ContinueStatement) End() Position {
+	return positionOr(cs.Semicolon, positionOf(cs.Token))
+}
+
+func (bs *BlockStatement) Start() Position {
+	if bs == nil {
+		return Position{}
+	}
+	return positionOf(bs.Token)
+}
+func (bs *BlockStatement) End() Position {
+	if bs == nil {
+		return Position{}
+	}
+	if len(bs.Statements) == 0 {
+		return positionOr(bs.Rbrace, positionOf(bs.Token))
+	}
+	return positionOr(bs.Rbrace, bs.Statements[len(bs.Statements)-1].End())
+}
+
+func (es *ExpressionStatement) Start() Position { return positionOf(es.Token) }
+func (es *ExpressionStatement) End() Position {
+	return positionOr(es.Semicolon, endOf(es.Expression))
+}
+
+func (pe *PrefixExpression) Start() Position { return positionOf(pe.Token) }
+func (pe *PrefixExpression) End() Position   { return endOf(pe.Right) }
+
+func (ie *InfixExpression) Start() Position { return startOf(ie.Left) }
+func (ie *InfixExpression) End() Position   { return endOf(ie.Right) }
+
+func (cc *ComparisonChain) Start() Position {
+	if len(cc.Operands) == 0 {
+		return positionOf(cc.Token)
+	}
+	return startOf(cc.Operands[0])
+}
+func (cc *ComparisonChain) End() Position {
+	if len(cc.Operands) == 0 {
+		return positionOf(cc.Token)
+	}
+	return endOf(cc.Operands[len(cc.Operands)-1])
+}
+
+func (pe *PostfixExpression) Start() Position { return startOf(pe.Left) }
+func (pe *PostfixExpression) End() Position   { return positionOf(pe.Token) }
+
+func (ae *AssignExpression) Start() Position { return ae.Name.Start() }
+func (ae *AssignExpression) End() Position   { return endOf(ae.Value) }
+
+func (te *TernaryExpression) Start() Position { return startOf(te.Condition) }
+func (te *TernaryExpression) End() Position   { return endOf(te.Alternative) }
+
+func (ie *IfExpression) Start() Position { return positionOf(ie.Token) }
+func (ie *IfExpression) End() Position {
+	if ie.Alternative != nil {
+		return ie.Alternative.End()
+	}
+	return ie.Consequence.End()
+}
+
+func (be *BlockExpression) Start() Position { return positionOf(be.Token) }
+func (be *BlockExpression) End() Position   { return be.Block.End() }
+
+func (fl *FunctionLiteral) Start() Position { return positionOf(fl.Token) }
+func (fl *FunctionLiteral) End() Position   { return fl.Body.End() }
+
+func (ml *MacroLiteral) Start() Position { return positionOf(ml.Token) }
+func (ml *MacroLiteral) End() Position   { return ml.Body.End() }
+
+func (ce *CallExpression) Start() Position { return startOf(ce.Function) }
+func (ce *CallExpression) End() Position {
+	if len(ce.Arguments) == 0 {
+		return positionOr(ce.Rparen, positionOf(ce.Token))
+	}
+	return positionOr(ce.Rparen, endOf(ce.Arguments[len(ce.Arguments)-1]))
+}
+
+func (se *SpreadExpression) Start() Position { return positionOf(se.Token) }
+func (se *SpreadExpression) End() Position   { return endOf(se.Value) }
+
+func (al *ArrayLiteral) Start() Position { return positionOf(al.Token) }
+func (al *ArrayLiteral) End() Position {
+	if len(al.Elements) == 0 {
+		return positionOr(al.Rbracket, positionOf(al.Token))
+	}
+	return positionOr(al.Rbracket, endOf(al.Elements[len(al.Elements)-1]))
+}
+
+func (ie *IndexExpression) Start() Position { return startOf(ie.Left) }
+func (ie *IndexExpression) End() Position {
+	return positionOr(ie.Rbracket, endOf(ie.Index))
+}
+
+func (hl *HashLiteral) Start() Position { return positionOf(hl.Token) }
+func (hl *HashLiteral) End() Position {
+	if len(hl.Pairs) == 0 {
+		return positionOr(hl.Rbrace, positionOf(hl.Token))
+	}
+	return positionOr(hl.Rbrace, endOf(hl.Pairs[len(hl.Pairs)-1].Value))
+}
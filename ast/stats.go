@@ -0,0 +1,15 @@
+package ast
+
+import "reflect"
+
+// プログラムに含まれるノードの数を、ノードの型名ごとに数える
+// 例: {"Program": 1, "LetStatement": 2, "InfixExpression": 3, ...}
+// 生成されたプログラムの形を調べたり、異常に深い入れ子を見つけたりするためのもの
+func Stats(p *Program) map[string]int {
+	stats := map[string]int{}
+	Walk(p, func(node Node) bool {
+		stats[reflect.TypeOf(node).Elem().Name()]++
+		return true
+	})
+	return stats
+}
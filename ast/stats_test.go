@@ -0,0 +1,70 @@
+package ast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	// let x = 1 + 2 * 3;
+	// if (x > 5) { puts(x); }
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "x"},
+				Value: &InfixExpression{
+					Left:     &IntegerLiteral{Value: 1},
+					Operator: "+",
+					Right: &InfixExpression{
+						Left:     &IntegerLiteral{Value: 2},
+						Operator: "*",
+						Right:    &IntegerLiteral{Value: 3},
+					},
+				},
+			},
+			&ExpressionStatement{
+				Expression: &IfExpression{
+					Condition: &InfixExpression{
+						Left:     &Identifier{Value: "x"},
+						Operator: ">",
+						Right:    &IntegerLiteral{Value: 5},
+					},
+					Consequence: &BlockStatement{
+						Statements: []Statement{
+							&ExpressionStatement{
+								Expression: &CallExpression{
+									Function:  &Identifier{Value: "puts"},
+									Arguments: []Expression{&Identifier{Value: "x"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	expected := map[string]int{
+		"Program":             1,
+		"LetStatement":        1,
+		"ExpressionStatement": 2,
+		"IfExpression":        1,
+		"BlockStatement":      1,
+		"CallExpression":      1,
+		"InfixExpression":     3,
+		"Identifier":          4,
+		"IntegerLiteral":      4,
+	}
+
+	stats := Stats(program)
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("wrong stats.\nwant=%v\ngot=%v", expected, stats)
+	}
+}
+
+func TestStatsEmptyProgram(t *testing.T) {
+	stats := Stats(&Program{})
+	if len(stats) != 1 || stats["Program"] != 1 {
+		t.Errorf("wrong stats for empty program. got=%v", stats)
+	}
+}
@@ -0,0 +1,158 @@
+package ast
+
+type WalkFunc func(Node) bool
+
+// 深さ優先でノードを訪問する
+// fnがtrueを返したときだけそのノードの子を訪問する
+func Walk(node Node, fn WalkFunc) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, s := range node.Statements {
+			Walk(s, fn)
+		}
+	case *LetStatement:
+		if node.Name != nil {
+			Walk(node.Name, fn)
+		}
+		if node.Value != nil {
+			Walk(node.Value, fn)
+		}
+	case *MultiLetStatement:
+		for _, n := range node.Names {
+			Walk(n, fn)
+		}
+		for _, v := range node.Values {
+			Walk(v, fn)
+		}
+	case *ReturnStatement:
+		if node.ReturnValue != nil {
+			Walk(node.ReturnValue, fn)
+		}
+	case *WhileStatement:
+		if node.Condition != nil {
+			Walk(node.Condition, fn)
+		}
+		if node.Body != nil {
+			Walk(node.Body, fn)
+		}
+	case *ForStatement:
+		if node.Init != nil {
+			Walk(node.Init, fn)
+		}
+		if node.Condition != nil {
+			Walk(node.Condition, fn)
+		}
+		if node.Post != nil {
+			Walk(node.Post, fn)
+		}
+		if node.Body != nil {
+			Walk(node.Body, fn)
+		}
+	case *BlockStatement:
+		for _, s := range node.Statements {
+			Walk(s, fn)
+		}
+	case *ExpressionStatement:
+		if node.Expression != nil {
+			Walk(node.Expression, fn)
+		}
+	case *PrefixExpression:
+		if node.Right != nil {
+			Walk(node.Right, fn)
+		}
+	case *InfixExpression:
+		if node.Left != nil {
+			Walk(node.Left, fn)
+		}
+		if node.Right != nil {
+			Walk(node.Right, fn)
+		}
+	case *ComparisonChain:
+		for _, operand := range node.Operands {
+			Walk(operand, fn)
+		}
+	case *IfExpression:
+		if node.Condition != nil {
+			Walk(node.Condition, fn)
+		}
+		if node.Consequence != nil {
+			Walk(node.Consequence, fn)
+		}
+		if node.Alternative != nil {
+			Walk(node.Alternative, fn)
+		}
+	case *BlockExpression:
+		if node.Block != nil {
+			Walk(node.Block, fn)
+		}
+	case *FunctionLiteral:
+		for _, p := range node.Parameters {
+			Walk(p, fn)
+		}
+		if node.Body != nil {
+			Walk(node.Body, fn)
+		}
+	case *CallExpression:
+		if node.Function != nil {
+			Walk(node.Function, fn)
+		}
+		for _, a := range node.Arguments {
+			Walk(a, fn)
+		}
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			Walk(el, fn)
+		}
+	case *TernaryExpression:
+		if node.Condition != nil {
+			Walk(node.Condition, fn)
+		}
+		if node.Consequence != nil {
+			Walk(node.Consequence, fn)
+		}
+		if node.Alternative != nil {
+			Walk(node.Alternative, fn)
+		}
+	case *PostfixExpression:
+		if node.Left != nil {
+			Walk(node.Left, fn)
+		}
+	case *SpreadExpression:
+		if node.Value != nil {
+			Walk(node.Value, fn)
+		}
+	case *AssignExpression:
+		if node.Name != nil {
+			Walk(node.Name, fn)
+		}
+		if node.Value != nil {
+			Walk(node.Value, fn)
+		}
+	case *IndexExpression:
+		if node.Left != nil {
+			Walk(node.Left, fn)
+		}
+		if node.Index != nil {
+			Walk(node.Index, fn)
+		}
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			Walk(pair.Key, fn)
+			Walk(pair.Value, fn)
+		}
+	case *MacroLiteral:
+		for _, p := range node.Parameters {
+			Walk(p, fn)
+		}
+		if node.Body != nil {
+			Walk(node.Body, fn)
+		}
+	case *Identifier, *IntegerLiteral, *Boolean, *StringLiteral, *NullLiteral,
+		*BreakStatement, *ContinueStatement:
+		// 子を持たない
+	}
+}
@@ -0,0 +1,99 @@
+package ast
+
+import (
+	"testing"
+)
+
+func TestWalk(t *testing.T) {
+	// let x = 1 + 2 * 3;
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: &Identifier{Value: "x"},
+				Value: &InfixExpression{
+					Left:     &IntegerLiteral{Value: 1},
+					Operator: "+",
+					Right: &InfixExpression{
+						Left:     &IntegerLiteral{Value: 2},
+						Operator: "*",
+						Right:    &IntegerLiteral{Value: 3},
+					},
+				},
+			},
+		},
+	}
+
+	// Program, LetStatement, Identifier, InfixExpression x2, IntegerLiteral x3
+	count := 0
+	Walk(program, func(node Node) bool {
+		count++
+		return true
+	})
+	if count != 8 {
+		t.Errorf("wrong number of nodes visited. want=%d, got=%d", 8, count)
+	}
+
+	// InfixExpressionの子は訪問しない
+	count = 0
+	Walk(program, func(node Node) bool {
+		count++
+		_, ok := node.(*InfixExpression)
+		return !ok
+	})
+	if count != 4 {
+		t.Errorf("wrong number of nodes visited. want=%d, got=%d", 4, count)
+	}
+}
+
+func TestWalkVisitsChildren(t *testing.T) {
+	integers := func(node Node) []int64 {
+		values := []int64{}
+		Walk(node, func(n Node) bool {
+			if integer, ok := n.(*IntegerLiteral); ok {
+				values = append(values, integer.Value)
+			}
+			return true
+		})
+		return values
+	}
+	one := func() Expression { return &IntegerLiteral{Value: 1} }
+
+	tests := []Node{
+		&ReturnStatement{ReturnValue: one()},
+		&PrefixExpression{Operator: "-", Right: one()},
+		&IfExpression{
+			Condition: one(),
+			Consequence: &BlockStatement{
+				Statements: []Statement{&ExpressionStatement{Expression: one()}},
+			},
+			Alternative: &BlockStatement{
+				Statements: []Statement{&ExpressionStatement{Expression: one()}},
+			},
+		},
+		&FunctionLiteral{
+			Parameters: []*Identifier{{Value: "a"}},
+			Body: &BlockStatement{
+				Statements: []Statement{&ExpressionStatement{Expression: one()}},
+			},
+		},
+		&CallExpression{Function: &Identifier{Value: "f"}, Arguments: []Expression{one(), one()}},
+		&ArrayLiteral{Elements: []Expression{one(), one()}},
+		&IndexExpression{Left: one(), Index: one()},
+		&HashLiteral{Pairs: []HashPair{{Key: one(), Value: one()}}},
+		&MacroLiteral{
+			Parameters: []*Identifier{{Value: "a"}},
+			Body: &BlockStatement{
+				Statements: []Statement{&ExpressionStatement{Expression: one()}},
+			},
+		},
+	}
+	expected := []int{1, 1, 3, 1, 2, 2, 2, 2, 1}
+
+	for i, node := range tests {
+		got := integers(node)
+		if len(got) != expected[i] {
+			t.Errorf("tests[%d] - %T: wrong number of integers visited. want=%d, got=%d",
+				i, node, expected[i], len(got))
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package code
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Assemble is the inverse of Instructions.String().
+// It parses a listing such as
+//
+//	0000 OpConstant 1
+//	0003 OpAdd
+//
+// and re-encodes every line with Make. The leading offset is optional and
+// only used for readability; blank lines are ignored.
+func Assemble(text string) (Instructions, error) {
+	ins := Instructions{}
+
+	for i, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[0]); err == nil {
+			fields = fields[1:]
+		}
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("line %d: missing opcode", i+1)
+		}
+
+		op, ok := lookupName(fields[0])
+		if !ok {
+			return nil, fmt.Errorf("line %d: unknown opcode %q", i+1, fields[0])
+		}
+		def := definitions[op]
+
+		args := fields[1:]
+		if len(args) != len(def.OperandWidths) {
+			return nil, fmt.Errorf("line %d: %s expects %d operands, got %d",
+				i+1, def.Name, len(def.OperandWidths), len(args))
+		}
+		operands := make([]int, len(args))
+		for j, arg := range args {
+			operand, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid operand %q", i+1, arg)
+			}
+			operands[j] = operand
+		}
+
+		ins = append(ins, Make(op, operands...)...)
+	}
+	return ins, nil
+}
+
+// lookupName finds the opcode whose definition has the given name
+func lookupName(name string) (Opcode, bool) {
+	for op, def := range definitions {
+		if def.Name == name {
+			return op, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,64 @@
+package code
+
+import "testing"
+
+func TestAssembleRoundTrip(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpConstant, 1),
+		Make(OpConstant, 65535),
+		Make(OpAdd),
+		Make(OpGetLocal, 255),
+		Make(OpJumpNotTruthy, 3),
+		Make(OpConstantWide, 70000),
+		Make(OpLoop, 0),
+		Make(OpPop),
+	}
+	original := Instructions{}
+	for _, ins := range instructions {
+		original = append(original, ins...)
+	}
+
+	assembled, err := Assemble(original.String())
+	if err != nil {
+		t.Fatalf("Assemble returned error: %s", err)
+	}
+	if string(assembled) != string(original) {
+		t.Errorf("round trip mismatch.\nwant=%q\ngot=%q", original.String(), assembled.String())
+	}
+}
+
+func TestAssembleWithoutOffsets(t *testing.T) {
+	assembled, err := Assemble("OpConstant 2\n\nOpPop\n")
+	if err != nil {
+		t.Fatalf("Assemble returned error: %s", err)
+	}
+	expected := append(Make(OpConstant, 2), Make(OpPop)...)
+	if string(assembled) != string(expected) {
+		t.Errorf("wrong instructions.\nwant=%q\ngot=%q",
+			Instructions(expected).String(), assembled.String())
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0000 OpNope", `line 1: unknown opcode "OpNope"`},
+		{"OpAdd\nOpConstant", "line 2: OpConstant expects 1 operands, got 0"},
+		{"OpAdd 1", "line 1: OpAdd expects 0 operands, got 1"},
+		{"OpConstant x", `line 1: invalid operand "x"`},
+		{"0000", "line 1: missing opcode"},
+	}
+
+	for _, tt := range tests {
+		_, err := Assemble(tt.input)
+		if err == nil {
+			t.Errorf("expected error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
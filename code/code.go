@@ -27,6 +27,80 @@ func (ins Instructions) String() string {
 	return out.String()
 }
 
+// StringAnnotated disassembles ins like String, but also prints the value
+// of the constant referenced by each OpConstant and OpConstantWide, e.g.
+// "OpConstant 1 (= 42)". constants holds the printed form of each entry in
+// the constant pool; an index outside it is marked as out of range.
+func (ins Instructions) StringAnnotated(constants []string) string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read, err := ReadOperandsChecked(def, ins[i+1:], nil)
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			break
+		}
+
+		line := ins.fmtInstruction(def, operands)
+		switch Opcode(ins[i]) {
+		case OpConstant, OpConstantWide:
+			if operands[0] < len(constants) {
+				line += fmt.Sprintf(" (= %s)", constants[operands[0]])
+			} else {
+				line += " (out of range)"
+			}
+		}
+		fmt.Fprintf(&out, "%04d %s\n", i, line)
+		i += 1 + read
+	}
+	return out.String()
+}
+
+// Iterate decodes each instruction in order and calls fn with its offset,
+// definition and operands. It stops at an undefined opcode or at an
+// instruction whose operands are cut off by the end of ins.
+func (ins Instructions) Iterate(fn func(offset int, def *Definition, operands []int)) {
+	i := 0
+	for i < len(ins) {
+		def, err := Lookup(ins[i])
+		if err != nil {
+			return
+		}
+
+		width := 0
+		for _, w := range def.OperandWidths {
+			width += w
+		}
+		if i+1+width > len(ins) {
+			return
+		}
+
+		operands, read := ReadOperands(def, ins[i+1:])
+		fn(i, def, operands)
+		i += 1 + read
+	}
+}
+
+// LastOpcode returns the opcode of the last complete instruction in ins.
+// It reports false for an empty stream or one that starts with bad input.
+func (ins Instructions) LastOpcode() (Opcode, bool) {
+	var last Opcode
+	found := false
+	ins.Iterate(func(offset int, def *Definition, operands []int) {
+		last = Opcode(ins[offset])
+		found = true
+	})
+	return last, found
+}
+
 func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 	operandCount := len(def.OperandWidths)
 
@@ -75,6 +149,24 @@ const (
 	OpReturn      // for returning implicit vm.Null
 	OpGetLocal
 	OpSetLocal
+	OpGetBuiltin
+	OpGetFree
+	OpCurrentClosure // for self-recursive named functions
+	// for `&&` and `||`: jump keeping the operand on the stack, or pop it
+	OpJumpNotTruthyOrPop
+	OpJumpTruthyOrPop
+	OpConstantWide // OpConstant with a 4-byte operand for indexes above 65535
+	OpLoop         // backward jump to the absolute start of a loop
+	OpNot          // bitwise complement; OpBang is the logical `!`
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpPow
+	OpDup  // duplicate the top of the stack: [.. a] -> [.. a a]
+	OpSwap // swap the top two stack elements: [.. a b] -> [.. b a]
+	OpOver // copy the second element to the top: [.. a b] -> [.. a b a]
 )
 
 type Definition struct {
@@ -82,6 +174,12 @@ type Definition struct {
 	OperandWidths []int
 }
 
+// String formats the definition as its name followed by the operand
+// widths, e.g. "OpConstant(widths=[2])".
+func (d *Definition) String() string {
+	return fmt.Sprintf("%s(widths=%v)", d.Name, d.OperandWidths)
+}
+
 var definitions = map[Opcode]*Definition{
 	OpConstant: {
 		Name:          "OpConstant",
@@ -135,6 +233,46 @@ var definitions = map[Opcode]*Definition{
 		Name:          "OpBang",
 		OperandWidths: []int{},
 	},
+	OpNot: {
+		Name:          "OpNot",
+		OperandWidths: []int{},
+	},
+	OpBitAnd: {
+		Name:          "OpBitAnd",
+		OperandWidths: []int{},
+	},
+	OpBitOr: {
+		Name:          "OpBitOr",
+		OperandWidths: []int{},
+	},
+	OpBitXor: {
+		Name:          "OpBitXor",
+		OperandWidths: []int{},
+	},
+	OpShiftLeft: {
+		Name:          "OpShiftLeft",
+		OperandWidths: []int{},
+	},
+	OpShiftRight: {
+		Name:          "OpShiftRight",
+		OperandWidths: []int{},
+	},
+	OpPow: {
+		Name:          "OpPow",
+		OperandWidths: []int{},
+	},
+	OpDup: {
+		Name:          "OpDup",
+		OperandWidths: []int{},
+	},
+	OpSwap: {
+		Name:          "OpSwap",
+		OperandWidths: []int{},
+	},
+	OpOver: {
+		Name:          "OpOver",
+		OperandWidths: []int{},
+	},
 	OpJumpNotTruthy: {
 		Name:          "OpJumpNotTruthy",
 		OperandWidths: []int{2},
@@ -187,6 +325,44 @@ var definitions = map[Opcode]*Definition{
 		Name:          "OpSetLocal",
 		OperandWidths: []int{1},
 	},
+	OpGetBuiltin: {
+		Name:          "OpGetBuiltin",
+		OperandWidths: []int{1}, // = index of the builtin function
+	},
+	OpGetFree: {
+		Name:          "OpGetFree",
+		OperandWidths: []int{1}, // = index of the free variable
+	},
+	OpCurrentClosure: {
+		Name:          "OpCurrentClosure",
+		OperandWidths: []int{},
+	},
+	OpJumpNotTruthyOrPop: {
+		Name:          "OpJumpNotTruthyOrPop",
+		OperandWidths: []int{2},
+	},
+	OpJumpTruthyOrPop: {
+		Name:          "OpJumpTruthyOrPop",
+		OperandWidths: []int{2},
+	},
+	OpConstantWide: {
+		Name:          "OpConstantWide",
+		OperandWidths: []int{4}, // = four-byte operand
+	},
+	OpLoop: {
+		Name:          "OpLoop",
+		OperandWidths: []int{2},
+	},
+}
+
+// String returns the name of the opcode, e.g. "OpAdd",
+// or "UNKNOWN(<n>)" when the opcode is not defined
+func (op Opcode) String() string {
+	def, ok := definitions[op]
+	if !ok {
+		return fmt.Sprintf("UNKNOWN(%d)", byte(op))
+	}
+	return def.Name
 }
 
 // Lookup takes a byte of Opcode,
@@ -199,11 +375,28 @@ func Lookup(op byte) (*Definition, error) {
 	return def, nil
 }
 
+// Make encodes an instruction. It returns an empty slice for an undefined
+// opcode or the wrong number of operands; use MakeE to find out which.
 func Make(op Opcode, operands ...int) []byte {
-	def, ok := definitions[op]
-	if !ok {
+	instruction, err := MakeE(op, operands...)
+	if err != nil {
 		return []byte{}
 	}
+	return instruction
+}
+
+// MakeE is Make with an error for an undefined opcode or an operand count
+// that doesn't match the definition's OperandWidths. Operand values are
+// truncated to their widths as in Make; MakeChecked also rejects those.
+func MakeE(op Opcode, operands ...int) ([]byte, error) {
+	def, err := Lookup(byte(op))
+	if err != nil {
+		return nil, err
+	}
+	if len(operands) != len(def.OperandWidths) {
+		return nil, fmt.Errorf("%s expects %d operands, got %d",
+			def.Name, len(def.OperandWidths), len(operands))
+	}
 
 	instructionLen := 1
 	for _, w := range def.OperandWidths {
@@ -218,6 +411,8 @@ func Make(op Opcode, operands ...int) []byte {
 	for i, o := range operands {
 		width := def.OperandWidths[i]
 		switch width {
+		case 4:
+			binary.BigEndian.PutUint32(instruction[offset:], uint32(o))
 		case 2:
 			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
 		case 1:
@@ -225,7 +420,34 @@ func Make(op Opcode, operands ...int) []byte {
 		}
 		offset += width
 	}
-	return instruction
+	return instruction, nil
+}
+
+// MakeChecked is a debugging variant of Make that reports malformed
+// instructions instead of silently encoding them: an undefined opcode, the
+// wrong number of operands, an operand that doesn't fit its width, or an
+// odd OpHash count (the operand counts keys plus values, so it must be
+// even). Any of these means the compiler has a bug.
+func MakeChecked(op Opcode, operands ...int) ([]byte, error) {
+	instruction, err := MakeE(op, operands...)
+	if err != nil {
+		return nil, err
+	}
+
+	def := definitions[op]
+	for i, o := range operands {
+		width := def.OperandWidths[i]
+		if o < 0 || uint64(o) >= uint64(1)<<(8*uint(width)) {
+			return nil, fmt.Errorf("%s operand %d out of range: %d does not fit in %d bytes",
+				def.Name, i, o, width)
+		}
+	}
+
+	if op == OpHash && operands[0]%2 != 0 {
+		return nil, fmt.Errorf("OpHash operand must be even, got %d", operands[0])
+	}
+
+	return instruction, nil
 }
 
 // ReadOperands is the function that reverses everything 'Make' does
@@ -236,6 +458,8 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 
 	for i, width := range def.OperandWidths {
 		switch width {
+		case 4:
+			operands[i] = int(ReadUint32(ins[offset:]))
 		case 2:
 			operands[i] = int(ReadUint16(ins[offset:]))
 		case 1:
@@ -246,10 +470,49 @@ func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
 	return operands, offset
 }
 
+// ReadOperandsChecked is a debugging variant of ReadOperands. Instead of
+// panicking on truncated input or silently returning a wrapped value, it
+// returns an error. bounds holds an exclusive upper bound for each operand,
+// such as len(ins) for a jump target or the size of the constant pool for
+// an OpConstant index; a bound of 0 or a missing bound is not checked.
+func ReadOperandsChecked(def *Definition, ins Instructions, bounds []int) ([]int, int, error) {
+	width := 0
+	for _, w := range def.OperandWidths {
+		width += w
+	}
+	if len(ins) < width {
+		return nil, 0, fmt.Errorf("%s operands truncated: want %d bytes, got %d",
+			def.Name, width, len(ins))
+	}
+
+	operands, read := ReadOperands(def, ins)
+	for i, operand := range operands {
+		if i >= len(bounds) || bounds[i] <= 0 {
+			continue
+		}
+		if operand >= bounds[i] {
+			return nil, 0, fmt.Errorf("%s operand %d out of range: %d (must be < %d)",
+				def.Name, i, operand, bounds[i])
+		}
+	}
+	return operands, read, nil
+}
+
+func ReadUint32(ins Instructions) uint32 {
+	return binary.BigEndian.Uint32(ins)
+}
+
 func ReadUint16(ins Instructions) uint16 {
 	return binary.BigEndian.Uint16(ins)
 }
 
+// ReplaceUint16 overwrites the big-endian 2-byte operand starting at pos.
+// It is used to back-patch the placeholder operand of a forward jump once
+// the jump target is known.
+func (ins Instructions) ReplaceUint16(pos int, operand uint16) {
+	binary.BigEndian.PutUint16(ins[pos:], operand)
+}
+
 func ReadUint8(ins Instructions) uint8 {
 	return uint8(ins[0])
 }
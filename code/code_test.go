@@ -2,6 +2,8 @@ package code
 
 import (
 	// "github.com/k0kubun/pp"
+	"bytes"
+	"fmt"
 	"testing"
 )
 
@@ -27,6 +29,13 @@ func TestMake(t *testing.T) {
 				byte(OpConstant), 0, 254,
 			},
 		},
+		{
+			OpConstantWide,
+			[]int{70000},
+			[]byte{
+				byte(OpConstantWide), 0, 1, 17, 112,
+			},
+		},
 		{
 			OpAdd,
 			[]int{},
@@ -42,6 +51,14 @@ func TestMake(t *testing.T) {
 				255,
 			},
 		},
+		{
+			OpGetBuiltin,
+			[]int{3},
+			[]byte{
+				byte(OpGetBuiltin),
+				3,
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -64,11 +81,41 @@ func TestInstructionsString(t *testing.T) {
 		Make(OpGetLocal, 1),
 		Make(OpConstant, 2),
 		Make(OpConstant, 65535),
+		Make(OpGetBuiltin, 3),
+		Make(OpGetFree, 0),
+		Make(OpCurrentClosure),
+		Make(OpConstantWide, 70000),
+		Make(OpLoop, 7),
+		Make(OpNot),
+		Make(OpBitAnd),
+		Make(OpBitOr),
+		Make(OpBitXor),
+		Make(OpShiftLeft),
+		Make(OpShiftRight),
+		Make(OpPow),
+		Make(OpDup),
+		Make(OpSwap),
+		Make(OpOver),
 	}
 	expected := `0000 OpAdd
 0001 OpGetLocal 1
 0003 OpConstant 2
 0006 OpConstant 65535
+0009 OpGetBuiltin 3
+0011 OpGetFree 0
+0013 OpCurrentClosure
+0014 OpConstantWide 70000
+0019 OpLoop 7
+0022 OpNot
+0023 OpBitAnd
+0024 OpBitOr
+0025 OpBitXor
+0026 OpShiftLeft
+0027 OpShiftRight
+0028 OpPow
+0029 OpDup
+0030 OpSwap
+0031 OpOver
 `
 
 	concatted := Instructions{}
@@ -81,6 +128,83 @@ func TestInstructionsString(t *testing.T) {
 	}
 }
 
+func TestMakeE(t *testing.T) {
+	tests := []struct {
+		op            Opcode
+		operands      []int
+		expected      []byte
+		expectedError string
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}, ""},
+		{OpAdd, []int{}, []byte{byte(OpAdd)}, ""},
+		{OpAdd, []int{1}, nil, "OpAdd expects 0 operands, got 1"},
+		{OpConstant, []int{}, nil, "OpConstant expects 1 operands, got 0"},
+		{OpGetLocal, []int{1, 2}, nil, "OpGetLocal expects 1 operands, got 2"},
+		{Opcode(255), []int{}, nil, "opcode 255 undefined"},
+	}
+
+	for _, tt := range tests {
+		instruction, err := MakeE(tt.op, tt.operands...)
+		if tt.expectedError != "" {
+			if err == nil {
+				t.Errorf("expected error %q, got none", tt.expectedError)
+			} else if err.Error() != tt.expectedError {
+				t.Errorf("wrong error. want=%q, got=%q", tt.expectedError, err)
+			}
+			// Make keeps its old behaviour of returning an empty instruction
+			if got := Make(tt.op, tt.operands...); len(got) != 0 {
+				t.Errorf("Make should return an empty instruction, got %v", got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+		if !bytes.Equal(instruction, tt.expected) {
+			t.Errorf("instruction wrong. want=%v, got=%v", tt.expected, instruction)
+		}
+	}
+}
+
+func TestMakeChecked(t *testing.T) {
+	tests := []struct {
+		op            Opcode
+		operands      []int
+		expected      []byte
+		expectedError string
+	}{
+		{OpHash, []int{4}, []byte{byte(OpHash), 0, 4}, ""},
+		{OpHash, []int{0}, []byte{byte(OpHash), 0, 0}, ""},
+		{OpHash, []int{3}, nil, "OpHash operand must be even, got 3"},
+		{OpConstant, []int{65535}, []byte{byte(OpConstant), 255, 255}, ""},
+		{OpConstant, []int{65536}, nil, "OpConstant operand 0 out of range: 65536 does not fit in 2 bytes"},
+		{OpGetLocal, []int{-1}, nil, "OpGetLocal operand 0 out of range: -1 does not fit in 1 bytes"},
+		{OpAdd, []int{1}, nil, "OpAdd expects 0 operands, got 1"},
+		{OpConstant, []int{}, nil, "OpConstant expects 1 operands, got 0"},
+		{Opcode(255), []int{}, nil, "opcode 255 undefined"},
+	}
+
+	for _, tt := range tests {
+		instruction, err := MakeChecked(tt.op, tt.operands...)
+		if tt.expectedError != "" {
+			if err == nil {
+				t.Errorf("expected error %q, got none", tt.expectedError)
+			} else if err.Error() != tt.expectedError {
+				t.Errorf("wrong error. want=%q, got=%q", tt.expectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+		if !bytes.Equal(instruction, tt.expected) {
+			t.Errorf("instruction wrong. want=%v, got=%v", tt.expected, instruction)
+		}
+	}
+}
+
 func TestReadOperands(t *testing.T) {
 	tests := []struct {
 		op        Opcode
@@ -88,7 +212,15 @@ func TestReadOperands(t *testing.T) {
 		bytesRead int
 	}{
 		{OpConstant, []int{65535}, 2},
+		{OpConstantWide, []int{70000}, 4},
+		{OpLoop, []int{7}, 2},
+		{OpLoop, []int{65535}, 2},
 		{OpGetLocal, []int{255}, 1},
+		{OpGetBuiltin, []int{3}, 1},
+		{OpGetFree, []int{255}, 1},
+		{OpCurrentClosure, []int{}, 0},
+		{OpJumpNotTruthyOrPop, []int{65535}, 2},
+		{OpJumpTruthyOrPop, []int{65535}, 2},
 	}
 
 	for _, tt := range tests {
@@ -111,3 +243,232 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestInstructionsStringAnnotated(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpConstant, 0),
+		Make(OpConstant, 1),
+		Make(OpAdd),
+		Make(OpConstantWide, 2),
+		Make(OpConstant, 3),
+		Make(OpPop),
+	}
+	constants := []string{"42", "\"hello\"", "[1, 2]"}
+
+	expected := `0000 OpConstant 0 (= 42)
+0003 OpConstant 1 (= "hello")
+0006 OpAdd
+0007 OpConstantWide 2 (= [1, 2])
+0012 OpConstant 3 (out of range)
+0015 OpPop
+`
+
+	concatted := Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+
+	if got := concatted.StringAnnotated(constants); got != expected {
+		t.Errorf("instructions wrongly formatted. \nwant=%q\ngot=%q", expected, got)
+	}
+
+	truncated := append(Make(OpPop), byte(OpConstant), 0)
+	expected = "0000 OpPop\nERROR: OpConstant operands truncated: want 2 bytes, got 1\n"
+	if got := Instructions(truncated).StringAnnotated(constants); got != expected {
+		t.Errorf("truncated instructions wrongly formatted. \nwant=%q\ngot=%q", expected, got)
+	}
+}
+
+func TestReadOperandsChecked(t *testing.T) {
+	tests := []struct {
+		ins           Instructions
+		bounds        []int
+		expected      []int
+		expectedError string
+	}{
+		{Make(OpJump, 10), []int{20}, []int{10}, ""},
+		{Make(OpJump, 10), nil, []int{10}, ""},
+		{Make(OpJump, 65535), []int{0}, []int{65535}, ""},
+		{Make(OpJump, 65535), []int{20}, nil, "OpJump operand 0 out of range: 65535 (must be < 20)"},
+		{Make(OpConstant, 3), []int{3}, nil, "OpConstant operand 0 out of range: 3 (must be < 3)"},
+		{Make(OpGetLocal, 2), []int{2}, nil, "OpGetLocal operand 0 out of range: 2 (must be < 2)"},
+		{Make(OpConstantWide, 70000)[:3], []int{0}, nil, "OpConstantWide operands truncated: want 4 bytes, got 2"},
+	}
+
+	for _, tt := range tests {
+		def, err := Lookup(tt.ins[0])
+		if err != nil {
+			t.Fatalf("definition not found: %q\n", err)
+		}
+
+		operands, _, err := ReadOperandsChecked(def, tt.ins[1:], tt.bounds)
+		if tt.expectedError != "" {
+			if err == nil {
+				t.Errorf("expected error %q, got none", tt.expectedError)
+			} else if err.Error() != tt.expectedError {
+				t.Errorf("wrong error. want=%q, got=%q", tt.expectedError, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error: %s", err)
+			continue
+		}
+		for i, want := range tt.expected {
+			if operands[i] != want {
+				t.Errorf("operand wrong. want=%d, got=%d", want, operands[i])
+			}
+		}
+	}
+}
+
+func TestReplaceUint16(t *testing.T) {
+	ins := Instructions(append(Make(OpJump, 9999), Make(OpPop)...))
+
+	ins.ReplaceUint16(1, 7)
+
+	if got := ReadUint16(ins[1:]); got != 7 {
+		t.Errorf("operand wrong. want=7, got=%d", got)
+	}
+	if Opcode(ins[0]) != OpJump || Opcode(ins[3]) != OpPop {
+		t.Errorf("surrounding instructions changed. got=%q", ins.String())
+	}
+}
+
+func TestLastOpcode(t *testing.T) {
+	tests := []struct {
+		ins      Instructions
+		expected Opcode
+		found    bool
+	}{
+		{Instructions{}, 0, false},
+		{Make(OpAdd), OpAdd, true},
+		{append(append(Make(OpConstant, 1), Make(OpConstant, 2)...), Make(OpAdd)...), OpAdd, true},
+		{append(Make(OpAdd), Make(OpConstant, 65535)...), OpConstant, true},
+		// a truncated trailing instruction is not counted
+		{append(Make(OpPop), byte(OpConstant), 0), OpPop, true},
+		{Instructions{255}, 0, false},
+	}
+
+	for i, tt := range tests {
+		op, found := tt.ins.LastOpcode()
+		if found != tt.found {
+			t.Errorf("tests[%d] - found wrong. want=%t, got=%t", i, tt.found, found)
+			continue
+		}
+		if op != tt.expected {
+			t.Errorf("tests[%d] - opcode wrong. want=%s, got=%s", i, tt.expected, op)
+		}
+	}
+}
+
+func TestDefinitionString(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		expected string
+	}{
+		{OpAdd, "OpAdd(widths=[])"},
+		{OpConstant, "OpConstant(widths=[2])"},
+		{OpConstantWide, "OpConstantWide(widths=[4])"},
+	}
+
+	for _, tt := range tests {
+		def, err := Lookup(byte(tt.op))
+		if err != nil {
+			t.Fatalf("definition not found: %q\n", err)
+		}
+		if got := fmt.Sprint(def); got != tt.expected {
+			t.Errorf("wrong definition string. want=%q, got=%q", tt.expected, got)
+		}
+	}
+}
+
+func TestOpcodeString(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		expected string
+	}{
+		{OpAdd, "OpAdd"},
+		{OpConstant, "OpConstant"},
+		{OpLoop, "OpLoop"},
+		{Opcode(255), "UNKNOWN(255)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.op.String(); got != tt.expected {
+			t.Errorf("wrong name for opcode %d. want=%q, got=%q", byte(tt.op), tt.expected, got)
+		}
+		if got := fmt.Sprint(tt.op); got != tt.expected {
+			t.Errorf("fmt.Sprint wrong for opcode %d. want=%q, got=%q", byte(tt.op), tt.expected, got)
+		}
+	}
+}
+
+func TestIterate(t *testing.T) {
+	instructions := []Instructions{
+		Make(OpConstant, 1),
+		Make(OpGetLocal, 0),
+		Make(OpAdd),
+		Make(OpConstantWide, 70000),
+		Make(OpJumpNotTruthy, 0),
+		Make(OpPop),
+	}
+	ins := Instructions{}
+	for _, in := range instructions {
+		ins = append(ins, in...)
+	}
+
+	type visit struct {
+		offset   int
+		name     string
+		operands []int
+	}
+	expected := []visit{
+		{0, "OpConstant", []int{1}},
+		{3, "OpGetLocal", []int{0}},
+		{5, "OpAdd", []int{}},
+		{6, "OpConstantWide", []int{70000}},
+		{11, "OpJumpNotTruthy", []int{0}},
+		{14, "OpPop", []int{}},
+	}
+
+	visits := []visit{}
+	ins.Iterate(func(offset int, def *Definition, operands []int) {
+		visits = append(visits, visit{offset, def.Name, operands})
+	})
+
+	if len(visits) != len(expected) {
+		t.Fatalf("wrong number of instructions. want=%d, got=%d", len(expected), len(visits))
+	}
+	for i, want := range expected {
+		got := visits[i]
+		if got.offset != want.offset || got.name != want.name {
+			t.Errorf("visits[%d] wrong. want=%d %s, got=%d %s",
+				i, want.offset, want.name, got.offset, got.name)
+		}
+		if fmt.Sprint(got.operands) != fmt.Sprint(want.operands) {
+			t.Errorf("visits[%d] operands wrong. want=%v, got=%v", i, want.operands, got.operands)
+		}
+	}
+}
+
+func TestIterateStopsOnBadInput(t *testing.T) {
+	tests := []struct {
+		name     string
+		ins      Instructions
+		expected []int
+	}{
+		{"undefined opcode", append(Make(OpAdd), 255, byte(OpPop)), []int{0}},
+		{"truncated operand", append(Make(OpPop), byte(OpConstant), 0), []int{0}},
+	}
+
+	for _, tt := range tests {
+		offsets := []int{}
+		tt.ins.Iterate(func(offset int, def *Definition, operands []int) {
+			offsets = append(offsets, offset)
+		})
+		if fmt.Sprint(offsets) != fmt.Sprint(tt.expected) {
+			t.Errorf("%s: wrong offsets. want=%v, got=%v", tt.name, tt.expected, offsets)
+		}
+	}
+}
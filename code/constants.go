@@ -0,0 +1,31 @@
+package code
+
+// ConstantPool collects the constants referenced by OpConstant and reuses
+// the index of a constant that is already in the pool. The code package
+// doesn't know about object values, so equality is left to the caller.
+type ConstantPool struct {
+	equal     func(a, b interface{}) bool
+	constants []interface{}
+}
+
+// NewConstantPool returns an empty pool that compares constants with equal.
+func NewConstantPool(equal func(a, b interface{}) bool) *ConstantPool {
+	return &ConstantPool{equal: equal}
+}
+
+// AddConstant returns the index of a constant equal to value, appending
+// value to the pool if there is none.
+func (cp *ConstantPool) AddConstant(value interface{}) int {
+	for i, c := range cp.constants {
+		if cp.equal(c, value) {
+			return i
+		}
+	}
+	cp.constants = append(cp.constants, value)
+	return len(cp.constants) - 1
+}
+
+// Constants returns the pooled constants in index order.
+func (cp *ConstantPool) Constants() []interface{} {
+	return cp.constants
+}
@@ -0,0 +1,40 @@
+package code
+
+import "testing"
+
+func TestConstantPool(t *testing.T) {
+	pool := NewConstantPool(func(a, b interface{}) bool {
+		return a == b
+	})
+
+	tests := []struct {
+		value    interface{}
+		expected int
+	}{
+		{1, 0},
+		{2, 1},
+		{1, 0},
+		{"1", 2},
+		{2, 1},
+		{3, 3},
+		{"1", 2},
+	}
+
+	for _, tt := range tests {
+		index := pool.AddConstant(tt.value)
+		if index != tt.expected {
+			t.Errorf("wrong index for %#v. want=%d, got=%d", tt.value, tt.expected, index)
+		}
+	}
+
+	expected := []interface{}{1, 2, "1", 3}
+	constants := pool.Constants()
+	if len(constants) != len(expected) {
+		t.Fatalf("wrong number of constants. want=%d, got=%d", len(expected), len(constants))
+	}
+	for i, c := range expected {
+		if constants[i] != c {
+			t.Errorf("wrong constant at %d. want=%#v, got=%#v", i, c, constants[i])
+		}
+	}
+}
@@ -2,7 +2,7 @@ package compiler
 
 import (
 	"fmt"
-	"sort"
+	"math"
 
 	"github.com/tamurayoshiya/monkey/ast"
 	"github.com/tamurayoshiya/monkey/code"
@@ -18,6 +18,14 @@ type CompilationScope struct {
 	instructions        code.Instructions
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+	loops               []*loopContext // enclosing loops, innermost last
+}
+
+// loopContext collects the jumps emitted by `break` and `continue` inside a
+// loop body until the positions they target are known.
+type loopContext struct {
+	breakJumps    []int
+	continueJumps []int
 }
 
 type Compiler struct {
@@ -25,6 +33,10 @@ type Compiler struct {
 	symbolTable *SymbolTable
 	scopes      []CompilationScope
 	scopeIndex  int
+
+	// constant pool index of every string literal compiled so far, so that
+	// equal literals share a single *object.String
+	stringConstants map[string]int
 }
 
 func New() *Compiler {
@@ -39,6 +51,8 @@ func New() *Compiler {
 		symbolTable: NewSymbolTable(),
 		scopes:      []CompilationScope{mainScope},
 		scopeIndex:  0,
+
+		stringConstants: map[string]int{},
 	}
 }
 
@@ -46,6 +60,13 @@ func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
 	compiler := New()
 	compiler.symbolTable = s
 	compiler.constants = constants
+	for i, c := range constants {
+		if str, ok := c.(*object.String); ok {
+			if _, seen := compiler.stringConstants[str.Value]; !seen {
+				compiler.stringConstants[str.Value] = i
+			}
+		}
+	}
 	return compiler
 }
 
@@ -64,7 +85,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 		c.emit(code.OpPop)
+	case *ast.ComparisonChain:
+		return c.compileComparisonChain(node)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogicalExpression(node)
+		}
 		if node.Operator == "<" {
 			err := c.Compile(node.Right)
 			if err != nil {
@@ -95,6 +121,18 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "**":
+			c.emit(code.OpPow)
+		case "&":
+			c.emit(code.OpBitAnd)
+		case "|":
+			c.emit(code.OpBitOr)
+		case "^":
+			c.emit(code.OpBitXor)
+		case "<<":
+			c.emit(code.OpShiftLeft)
+		case ">>":
+			c.emit(code.OpShiftRight)
 		case ">":
 			c.emit(code.OpGreaterThan)
 		case "==":
@@ -114,6 +152,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpBang)
 		case "-":
 			c.emit(code.OpMinus)
+		case "~":
+			c.emit(code.OpNot)
 		default:
 			return fmt.Errorf("unknown operator %s", node.Operator)
 		}
@@ -121,13 +161,15 @@ func (c *Compiler) Compile(node ast.Node) error {
 		integer := &object.Integer{
 			Value: node.Value,
 		}
-		c.emit(code.OpConstant, c.addConstant(integer))
+		c.emitConstant(c.addConstant(integer))
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
 		} else {
 			c.emit(code.OpFalse)
 		}
+	case *ast.NullLiteral:
+		c.emit(code.OpNull)
 	case *ast.IfExpression:
 		err := c.Compile(node.Condition)
 		if err != nil {
@@ -139,8 +181,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
+		// like a block expression, a branch that doesn't end with an
+		// expression statement (a loop, a let) evaluates to null
 		if c.lastInstructionIs(code.OpPop) {
 			c.removeLastPop()
+		} else {
+			c.emit(code.OpNull)
 		}
 
 		// Emit an `OpJump` with a bogus value
@@ -157,10 +203,48 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 			if c.lastInstructionIs(code.OpPop) {
 				c.removeLastPop()
+			} else {
+				c.emit(code.OpNull)
 			}
 		}
 		afterAlternativePos := len(c.currentInstructions())
 		c.changeOperand(jumpPos, afterAlternativePos)
+	case *ast.TernaryExpression:
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+		// same layout as an if expression, with expressions instead of blocks
+		jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+		err = c.Compile(node.Consequence)
+		if err != nil {
+			return err
+		}
+		jumpPos := c.emit(code.OpJump, 9999)
+		c.changeOperand(jumpNotTruthyPos, len(c.currentInstructions()))
+
+		err = c.Compile(node.Alternative)
+		if err != nil {
+			return err
+		}
+		c.changeOperand(jumpPos, len(c.currentInstructions()))
+	case *ast.WhileStatement:
+		return c.compileWhileStatement(node)
+	case *ast.ForStatement:
+		return c.compileForStatement(node)
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("break outside loop")
+		}
+		// Emit an `OpJump` with a bogus value, patched to the loop exit
+		loop.breakJumps = append(loop.breakJumps, c.emit(code.OpJump, 9999))
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("continue outside loop")
+		}
+		loop.continueJumps = append(loop.continueJumps, c.emit(code.OpJump, 9999))
 	case *ast.BlockStatement:
 		for _, s := range node.Statements {
 			err := c.Compile(s)
@@ -168,6 +252,18 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return err
 			}
 		}
+	case *ast.BlockExpression:
+		err := c.Compile(node.Block)
+		if err != nil {
+			return err
+		}
+		// the value of the last expression statement is the block's value;
+		// a block that doesn't end with one evaluates to null
+		if c.lastInstructionIs(code.OpPop) {
+			c.removeLastPop()
+		} else {
+			c.emit(code.OpNull)
+		}
 	case *ast.LetStatement:
 		err := c.Compile(node.Value)
 		if err != nil {
@@ -179,19 +275,70 @@ func (c *Compiler) Compile(node ast.Node) error {
 		} else {
 			c.emit(code.OpSetLocal, symbol.Index)
 		}
+	case *ast.MultiLetStatement:
+		// every value is on the stack before any name is bound, so the last
+		// value is on top and the names are set in reverse order
+		for _, v := range node.Values {
+			err := c.Compile(v)
+			if err != nil {
+				return err
+			}
+		}
+		symbols := make([]Symbol, len(node.Names))
+		for i, name := range node.Names {
+			symbols[i] = c.symbolTable.Define(name.Value)
+		}
+		for i := len(symbols) - 1; i >= 0; i-- {
+			if symbols[i].Scope == GlobalScope {
+				c.emit(code.OpSetGlobal, symbols[i].Index)
+			} else {
+				c.emit(code.OpSetLocal, symbols[i].Index)
+			}
+		}
+	case *ast.AssignExpression:
+		// the parser only builds identifier targets, but an AST assembled
+		// elsewhere (e.g. by a macro) may not have one
+		if node.Name == nil {
+			return fmt.Errorf("invalid assignment target")
+		}
+		symbol, ok := c.symbolTable.Resolve(node.Name.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", node.Name.Value)
+		}
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		// an assignment is an expression, so leave the assigned value on the stack
+		switch symbol.Scope {
+		case GlobalScope:
+			c.emit(code.OpSetGlobal, symbol.Index)
+			c.emit(code.OpGetGlobal, symbol.Index)
+		case LocalScope:
+			c.emit(code.OpSetLocal, symbol.Index)
+			c.emit(code.OpGetLocal, symbol.Index)
+		default:
+			return fmt.Errorf("cannot assign to %s variable %s", symbol.Scope, node.Name.Value)
+		}
+	case *ast.PostfixExpression:
+		return c.compilePostfixExpression(node)
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
 			return fmt.Errorf("undefined variable %s", node.Value)
 		}
-		if symbol.Scope == GlobalScope {
+		switch symbol.Scope {
+		case GlobalScope:
 			c.emit(code.OpGetGlobal, symbol.Index)
-		} else {
+		case LocalScope:
 			c.emit(code.OpGetLocal, symbol.Index)
+		default:
+			// free variables and self-references need closures, which the VM
+			// does not implement yet (OpGetFree, OpCurrentClosure)
+			return fmt.Errorf("unsupported %s variable %s", symbol.Scope, node.Value)
 		}
 	case *ast.StringLiteral:
-		str := &object.String{Value: node.Value}
-		c.emit(code.OpConstant, c.addConstant(str))
+		c.emitConstant(c.addStringConstant(node.Value))
 	case *ast.ArrayLiteral:
 		for _, el := range node.Elements {
 			err := c.Compile(el)
@@ -201,24 +348,22 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(code.OpArray, len(node.Elements))
 	case *ast.HashLiteral:
-		keys := []ast.Expression{}
-		for k := range node.Pairs {
-			keys = append(keys, k)
-		}
-		sort.Slice(keys, func(i, j int) bool {
-			return keys[i].String() < keys[j].String()
-		})
-		for _, k := range keys {
-			err := c.Compile(k)
+		// pairs are emitted in source order so side effects in keys and
+		// values happen left to right, as in the evaluator
+		for _, pair := range node.Pairs {
+			err := c.Compile(pair.Key)
 			if err != nil {
 				return err
 			}
-			err = c.Compile(node.Pairs[k])
+			err = c.Compile(pair.Value)
 			if err != nil {
 				return err
 			}
 		}
 		c.emit(code.OpHash, len(node.Pairs)*2)
+	case *ast.SpreadExpression:
+		// the number of arguments would only be known at run time
+		return fmt.Errorf("spread arguments are not supported by the compiler")
 	case *ast.IndexExpression:
 		err := c.Compile(node.Left)
 		if err != nil {
@@ -251,7 +396,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			NumLocals:     numLocals,
 			NumParameters: len(node.Parameters),
 		}
-		c.emit(code.OpConstant, c.addConstant(compiledFn))
+		c.emitConstant(c.addConstant(compiledFn))
 	case *ast.ReturnStatement:
 		err := c.Compile(node.ReturnValue)
 		if err != nil {
@@ -259,6 +404,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(code.OpReturnValue)
 	case *ast.CallExpression:
+		if length, ok := c.foldLenCall(node); ok {
+			integer := &object.Integer{Value: length}
+			c.emitConstant(c.addConstant(integer))
+			return nil
+		}
 		err := c.Compile(node.Function)
 		if err != nil {
 			return err
@@ -274,6 +424,41 @@ func (c *Compiler) Compile(node ast.Node) error {
 	return nil
 }
 
+// compilePostfixExpression compiles `x++` and `x--`. The old value is
+// duplicated before the update so that it is left as the expression's result:
+// get x, dup, constant 1, add (or sub), set x.
+func (c *Compiler) compilePostfixExpression(node *ast.PostfixExpression) error {
+	ident, ok := node.Left.(*ast.Identifier)
+	if !ok {
+		return fmt.Errorf("invalid assignment target")
+	}
+	symbol, ok := c.symbolTable.Resolve(ident.Value)
+	if !ok {
+		return fmt.Errorf("undefined variable %s", ident.Value)
+	}
+	if symbol.Scope != GlobalScope && symbol.Scope != LocalScope {
+		return fmt.Errorf("cannot assign to %s variable %s", symbol.Scope, ident.Value)
+	}
+
+	err := c.Compile(ident)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpDup)
+	c.emitConstant(c.addConstant(&object.Integer{Value: 1}))
+	if node.Operator == "++" {
+		c.emit(code.OpAdd)
+	} else {
+		c.emit(code.OpSub)
+	}
+	if symbol.Scope == GlobalScope {
+		c.emit(code.OpSetGlobal, symbol.Index)
+	} else {
+		c.emit(code.OpSetLocal, symbol.Index)
+	}
+	return nil
+}
+
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
@@ -286,11 +471,250 @@ func (c *Compiler) Bytecode() *Bytecode {
 	}
 }
 
+// compileWhileStatement lays out a while loop as
+//
+//	start: <condition>; OpJumpNotTruthy exit; <body>; OpLoop start
+//	exit:
+//
+// where `continue` jumps back to the condition check.
+func (c *Compiler) compileWhileStatement(node *ast.WhileStatement) error {
+	startPos := len(c.currentInstructions())
+	err := c.Compile(node.Condition)
+	if err != nil {
+		return err
+	}
+	jumpNotTruthyPos := c.emit(code.OpJumpNotTruthy, 9999)
+
+	c.enterLoop()
+	err = c.Compile(node.Body)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpLoop, startPos)
+
+	exitPos := len(c.currentInstructions())
+	c.changeOperand(jumpNotTruthyPos, exitPos)
+	c.leaveLoop(startPos, exitPos)
+	return nil
+}
+
+// compileForStatement lays out a for loop as
+//
+//	<init>; start: <condition>; OpJumpNotTruthy exit; <body>
+//	post: <post>; OpLoop start
+//	exit:
+//
+// where `continue` jumps to the post statement. Without a condition the
+// loop only ends through break or return.
+func (c *Compiler) compileForStatement(node *ast.ForStatement) error {
+	if node.Init != nil {
+		err := c.Compile(node.Init)
+		if err != nil {
+			return err
+		}
+	}
+
+	startPos := len(c.currentInstructions())
+	jumpNotTruthyPos := -1
+	if node.Condition != nil {
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+		jumpNotTruthyPos = c.emit(code.OpJumpNotTruthy, 9999)
+	}
+
+	c.enterLoop()
+	err := c.Compile(node.Body)
+	if err != nil {
+		return err
+	}
+
+	postPos := len(c.currentInstructions())
+	if node.Post != nil {
+		err := c.Compile(node.Post)
+		if err != nil {
+			return err
+		}
+	}
+	c.emit(code.OpLoop, startPos)
+
+	exitPos := len(c.currentInstructions())
+	if jumpNotTruthyPos != -1 {
+		c.changeOperand(jumpNotTruthyPos, exitPos)
+	}
+	c.leaveLoop(postPos, exitPos)
+	return nil
+}
+
+func (c *Compiler) enterLoop() {
+	scope := &c.scopes[c.scopeIndex]
+	scope.loops = append(scope.loops, &loopContext{})
+}
+
+// leaveLoop patches the jumps of the innermost loop's `continue` and
+// `break` statements and forgets the loop.
+func (c *Compiler) leaveLoop(continuePos, exitPos int) {
+	scope := &c.scopes[c.scopeIndex]
+	loop := scope.loops[len(scope.loops)-1]
+	scope.loops = scope.loops[:len(scope.loops)-1]
+
+	for _, pos := range loop.continueJumps {
+		c.changeOperand(pos, continuePos)
+	}
+	for _, pos := range loop.breakJumps {
+		c.changeOperand(pos, exitPos)
+	}
+}
+
+// currentLoop returns the innermost loop of the current compilation scope,
+// so a function literal inside a loop cannot break out of it.
+func (c *Compiler) currentLoop() *loopContext {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+// compileLogicalExpression compiles `&&` and `||` so that the right operand
+// is only evaluated when needed and the deciding operand is the result,
+// matching the evaluator.
+func (c *Compiler) compileLogicalExpression(node *ast.InfixExpression) error {
+	err := c.Compile(node.Left)
+	if err != nil {
+		return err
+	}
+
+	op := code.OpJumpNotTruthyOrPop
+	if node.Operator == "||" {
+		op = code.OpJumpTruthyOrPop
+	}
+	// Emit the jump with a bogus value, patched once Right is compiled
+	jumpPos := c.emit(op, 9999)
+
+	err = c.Compile(node.Right)
+	if err != nil {
+		return err
+	}
+	c.changeOperand(jumpPos, len(c.currentInstructions()))
+	return nil
+}
+
+// compileComparisonChain compiles a < b < c like (a < b) && (b < c), but
+// evaluates each operand once: a middle operand is kept on the stack below
+// the comparison result so the next comparison can reuse it. A failed
+// comparison jumps to the end with [.. b false], where b is dropped.
+func (c *Compiler) compileComparisonChain(node *ast.ComparisonChain) error {
+	err := c.Compile(node.Operands[0])
+	if err != nil {
+		return err
+	}
+
+	failJumps := []int{}
+	for i, operator := range node.Operators {
+		err := c.Compile(node.Operands[i+1])
+		if err != nil {
+			return err
+		}
+		last := i == len(node.Operators)-1
+		if !last {
+			// [.. a b] -> [.. b a b]
+			c.emit(code.OpSwap)
+			c.emit(code.OpOver)
+		}
+		err = c.emitComparison(operator)
+		if err != nil {
+			return err
+		}
+		if !last {
+			// Emit the jump with a bogus value, patched once the chain ends
+			failJumps = append(failJumps, c.emit(code.OpJumpNotTruthyOrPop, 9999))
+		}
+	}
+
+	endJump := c.emit(code.OpJump, 9999)
+	failPos := len(c.currentInstructions())
+	for _, pos := range failJumps {
+		c.changeOperand(pos, failPos)
+	}
+	c.emit(code.OpSwap)
+	c.emit(code.OpPop)
+	c.changeOperand(endJump, len(c.currentInstructions()))
+	return nil
+}
+
+// emitComparison emits the comparison of the top two stack elements.
+// `<` swaps them and uses OpGreaterThan, as for a plain a < b.
+func (c *Compiler) emitComparison(operator string) error {
+	switch operator {
+	case "<":
+		c.emit(code.OpSwap)
+		c.emit(code.OpGreaterThan)
+	case ">":
+		c.emit(code.OpGreaterThan)
+	case "==":
+		c.emit(code.OpEqual)
+	case "!=":
+		c.emit(code.OpNotEqual)
+	default:
+		return fmt.Errorf("unknown operator %s", operator)
+	}
+	return nil
+}
+
+// foldLenCall computes `len` at compile time when its argument is a string
+// literal or an array literal made only of literals, e.g. len([1, 2, 3]).
+// It is skipped when `len` has been shadowed by a user-defined binding.
+func (c *Compiler) foldLenCall(call *ast.CallExpression) (int64, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok || ident.Value != "len" || len(call.Arguments) != 1 {
+		return 0, false
+	}
+	if _, shadowed := c.symbolTable.Resolve(ident.Value); shadowed {
+		return 0, false
+	}
+	switch arg := call.Arguments[0].(type) {
+	case *ast.StringLiteral:
+		return int64(len(arg.Value)), true
+	case *ast.ArrayLiteral:
+		for _, el := range arg.Elements {
+			switch el.(type) {
+			case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+			default:
+				return 0, false
+			}
+		}
+		return int64(len(arg.Elements)), true
+	}
+	return 0, false
+}
+
 func (c *Compiler) addConstant(obj object.Object) int {
 	c.constants = append(c.constants, obj)
 	return len(c.constants) - 1
 }
 
+// emitConstant loads the constant at index, switching to the wide
+// instruction once the index no longer fits in OpConstant's operand.
+func (c *Compiler) emitConstant(index int) int {
+	if index > math.MaxUint16 {
+		return c.emit(code.OpConstantWide, index)
+	}
+	return c.emit(code.OpConstant, index)
+}
+
+// addStringConstant interns string literals: the same value always resolves
+// to the same constant index.
+func (c *Compiler) addStringConstant(value string) int {
+	if index, ok := c.stringConstants[value]; ok {
+		return index
+	}
+	index := c.addConstant(&object.String{Value: value})
+	c.stringConstants[value] = index
+	return index
+}
+
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	ins := code.Make(op, operands...)
 	pos := c.addInstruction(ins)
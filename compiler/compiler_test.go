@@ -9,6 +9,7 @@ import (
 	"github.com/tamurayoshiya/monkey/lexer"
 	"github.com/tamurayoshiya/monkey/object"
 	"github.com/tamurayoshiya/monkey/parser"
+	"github.com/tamurayoshiya/monkey/token"
 )
 
 type compilerTestCase struct {
@@ -84,6 +85,61 @@ func TestIntegerArithmetic(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			input:             "5 & 3",
+			expectedConstants: []interface{}{5, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpBitAnd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "5 | 3 ^ 1",
+			expectedConstants: []interface{}{5, 3, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpBitOr),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpBitXor),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1 << 4 >> 2",
+			expectedConstants: []interface{}{1, 4, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpShiftLeft),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpShiftRight),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "2 ** 3 ** 2",
+			expectedConstants: []interface{}{2, 3, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPow),
+				code.Make(code.OpPow),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "~5",
+			expectedConstants: []interface{}{5},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpNot),
+				code.Make(code.OpPop),
+			},
+		},
 	}
 	runCompilerTests(t, tests)
 }
@@ -231,6 +287,40 @@ func TestBooleanExpressions(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			input:             "1 < 2 < 3",
+			expectedConstants: []interface{}{1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpConstant, 1),
+				// 0006
+				code.Make(code.OpSwap),
+				// 0007
+				code.Make(code.OpOver),
+				// 0008
+				code.Make(code.OpSwap),
+				// 0009
+				code.Make(code.OpGreaterThan),
+				// 0010
+				code.Make(code.OpJumpNotTruthyOrPop, 21),
+				// 0013
+				code.Make(code.OpConstant, 2),
+				// 0016
+				code.Make(code.OpSwap),
+				// 0017
+				code.Make(code.OpGreaterThan),
+				// 0018
+				code.Make(code.OpJump, 23),
+				// 0021
+				code.Make(code.OpSwap),
+				// 0022
+				code.Make(code.OpPop),
+				// 0023
+				code.Make(code.OpPop),
+			},
+		},
 		{
 			input:             "1 == 2",
 			expectedConstants: []interface{}{1, 2},
@@ -338,6 +428,192 @@ func TestConditionals(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestNullLiteral(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "null",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpNull),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "while (true) { continue; break; }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 13),
+				// 0004 continue: back to the condition
+				code.Make(code.OpJump, 0),
+				// 0007 break: to the exit
+				code.Make(code.OpJump, 13),
+				// 0010
+				code.Make(code.OpLoop, 0),
+			},
+		},
+		{
+			input:             "let i = 0; for (; i; i = 1) { continue; break; }",
+			expectedConstants: []interface{}{0, 1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConstant, 0),
+				// 0003
+				code.Make(code.OpSetGlobal, 0),
+				// 0006
+				code.Make(code.OpGetGlobal, 0),
+				// 0009
+				code.Make(code.OpJumpNotTruthy, 31),
+				// 0012 continue: to the post statement
+				code.Make(code.OpJump, 18),
+				// 0015 break: to the exit
+				code.Make(code.OpJump, 31),
+				// 0018
+				code.Make(code.OpConstant, 1),
+				// 0021
+				code.Make(code.OpSetGlobal, 0),
+				// 0024
+				code.Make(code.OpGetGlobal, 0),
+				// 0027
+				code.Make(code.OpPop),
+				// 0028
+				code.Make(code.OpLoop, 6),
+			},
+		},
+		{
+			// break targets the innermost loop only
+			input:             "while (true) { while (false) { break; } break; }",
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 20),
+				// 0004
+				code.Make(code.OpFalse),
+				// 0005
+				code.Make(code.OpJumpNotTruthy, 14),
+				// 0008
+				code.Make(code.OpJump, 14),
+				// 0011
+				code.Make(code.OpLoop, 4),
+				// 0014
+				code.Make(code.OpJump, 20),
+				// 0017
+				code.Make(code.OpLoop, 0),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "break outside loop"},
+		{"continue;", "continue outside loop"},
+		{"while (true) { fn() { break; } }", "break outside loop"},
+	}
+	for _, tt := range errorTests {
+		err := New().Compile(parse(tt.input))
+		if err == nil {
+			t.Errorf("expected compile error for %q", tt.input)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+}
+
+func TestSpreadArgumentsUnsupported(t *testing.T) {
+	err := New().Compile(parse("let f = fn(a) { a }; f(...[1]);"))
+	if err == nil {
+		t.Fatalf("expected compile error for spread argument")
+	}
+	if err.Error() != "spread arguments are not supported by the compiler" {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
+
+func TestTernaryExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "true ? 10 : 20; 3333;",
+			expectedConstants: []interface{}{10, 20, 3333},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthy, 10),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJump, 13),
+				// 0010
+				code.Make(code.OpConstant, 1),
+				// 0013
+				code.Make(code.OpPop),
+				// 0014
+				code.Make(code.OpConstant, 2),
+				// 0017
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "true && 1",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJumpNotTruthyOrPop, 7),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "false || 1 && 2",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpFalse),
+				// 0001
+				code.Make(code.OpJumpTruthyOrPop, 13),
+				// 0004
+				code.Make(code.OpConstant, 0),
+				// 0007
+				code.Make(code.OpJumpNotTruthyOrPop, 13),
+				// 0010
+				code.Make(code.OpConstant, 1),
+				// 0013
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func TestGlobalLetStatement(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -404,6 +680,15 @@ func TestStringExpressions(t *testing.T) {
 				code.Make(code.OpAdd),
 				code.Make(code.OpPop),
 			},
+		}, {
+			input:             `"monkey" == "monkey"`,
+			expectedConstants: []interface{}{"monkey"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpEqual),
+				code.Make(code.OpPop),
+			},
 		},
 	}
 	runCompilerTests(t, tests)
@@ -491,6 +776,19 @@ func TestHashLiterals(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			// pairs keep their source order rather than being sorted
+			input:             `{"b": 1, "a": 2}`,
+			expectedConstants: []interface{}{"b", 1, "a", 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpHash, 4),
+				code.Make(code.OpPop),
+			},
+		},
 	}
 	runCompilerTests(t, tests)
 
@@ -847,3 +1145,284 @@ func TestLetStatementScopes(t *testing.T) {
 	runCompilerTests(t, tests)
 
 }
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			let x = 1;
+			x = 2;
+			`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			fn() {
+				let a = 1;
+				a = 2
+			}
+			`,
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+
+	program := parse("x = 1")
+	err := New().Compile(program)
+	if err == nil {
+		t.Fatalf("expected an error for assigning to an undefined variable")
+	}
+	if err.Error() != "undefined variable x" {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
+
+func TestUnsupportedSymbolScopes(t *testing.T) {
+	functionName := NewSymbolTable()
+	functionName.DefineFunctionName("fib")
+
+	tests := []struct {
+		input         string
+		symbolTable   *SymbolTable
+		expectedError string
+	}{
+		{"fn(a) { fn() { a } }", NewSymbolTable(), "unsupported FREE variable a"},
+		{"fib", functionName, "unsupported FUNCTION variable fib"},
+	}
+
+	for _, tt := range tests {
+		compiler := NewWithState(tt.symbolTable, []object.Object{})
+		err := compiler.Compile(parse(tt.input))
+		if err == nil {
+			t.Errorf("expected an error for %q, got none", tt.input)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, err.Error())
+		}
+	}
+}
+
+func TestMultiNameLetStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "let a, b = 1, 2;",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 1),
+				code.Make(code.OpSetGlobal, 0),
+			},
+		},
+		{
+			input: "fn() { let a, b = 1, 2; }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSetLocal, 1),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpReturn),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestPostfixExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+			let x = 1;
+			x++;
+			`,
+			expectedConstants: []interface{}{1, 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpDup),
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			fn() {
+				let a = 1;
+				a--
+			}
+			`,
+			expectedConstants: []interface{}{
+				1,
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpDup),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpSub),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+
+	err := New().Compile(parse("x++"))
+	if err == nil {
+		t.Fatalf("expected an error for incrementing an undefined variable")
+	}
+	if err.Error() != "undefined variable x" {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	err := New().Compile(parse("let x = 0; x = 1;"))
+	if err != nil {
+		t.Fatalf("unexpected compile error: %s", err)
+	}
+
+	// the parser refuses `5 = 1`, so build the node a macro could produce
+	node := &ast.AssignExpression{
+		Token: token.Token{Type: token.ASSIGN, Literal: "="},
+		Value: &ast.IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1},
+	}
+	err = New().Compile(node)
+	if err == nil {
+		t.Fatalf("expected an error for an assignment without a target")
+	}
+	if err.Error() != "invalid assignment target" {
+		t.Errorf("wrong error. got=%q", err.Error())
+	}
+}
+
+func TestWideConstants(t *testing.T) {
+	// fill the pool so the next constant lands beyond OpConstant's 2-byte operand
+	constants := make([]object.Object, 70000)
+	for i := range constants {
+		constants[i] = &object.Integer{Value: int64(i)}
+	}
+
+	compiler := NewWithState(NewSymbolTable(), constants)
+	err := compiler.Compile(parse("99999"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	bytecode := compiler.Bytecode()
+	expected := []code.Instructions{
+		code.Make(code.OpConstantWide, 70000),
+		code.Make(code.OpPop),
+	}
+	err = testInstructions(expected, bytecode.Instructions)
+	if err != nil {
+		t.Fatalf("testInstructions failed: %s", err)
+	}
+	err = testIntegerObject(99999, bytecode.Constants[70000])
+	if err != nil {
+		t.Fatalf("constant 70000 - testIntegerObject failed: %s", err)
+	}
+}
+
+func TestLenFolding(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `len([1, 2, 3])`,
+			expectedConstants: []interface{}{3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `len("abc")`,
+			expectedConstants: []interface{}{3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+			let len = fn(a) { 1 };
+			len([1, 2, 3]);
+			`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpReturnValue),
+				},
+				1, 2, 3,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 1),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpConstant, 4),
+				code.Make(code.OpArray, 3),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+
+	notFolded := []string{
+		`len(x)`,
+		`len([1, x])`,
+		`len([1], [2])`,
+	}
+	for _, input := range notFolded {
+		program := parse(input)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		call := stmt.Expression.(*ast.CallExpression)
+
+		if _, ok := New().foldLenCall(call); ok {
+			t.Errorf("len call should not be folded: %s", input)
+		}
+	}
+}
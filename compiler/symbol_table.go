@@ -3,8 +3,10 @@ package compiler
 type SymbolScope string
 
 const (
-	LocalScope  SymbolScope = "LOCAL"
-	GlobalScope SymbolScope = "GLOBAL"
+	LocalScope    SymbolScope = "LOCAL"
+	GlobalScope   SymbolScope = "GLOBAL"
+	FreeScope     SymbolScope = "FREE"
+	FunctionScope SymbolScope = "FUNCTION"
 )
 
 type Symbol struct {
@@ -17,11 +19,13 @@ type SymbolTable struct {
 	Outer          *SymbolTable
 	store          map[string]Symbol
 	numDefinitions int
+	FreeSymbols    []Symbol // original symbols of outer scopes captured by this scope
 }
 
 func NewSymbolTable() *SymbolTable {
 	s := make(map[string]Symbol)
-	return &SymbolTable{store: s}
+	free := []Symbol{}
+	return &SymbolTable{store: s, FreeSymbols: free}
 }
 
 func (s *SymbolTable) Define(name string) Symbol {
@@ -39,11 +43,45 @@ func (s *SymbolTable) Define(name string) Symbol {
 	return symbol
 }
 
+// DefineFree records original, a symbol of an enclosing local scope,
+// as a free variable of this scope and returns the free-scoped symbol
+func (s *SymbolTable) DefineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := Symbol{
+		Name:  original.Name,
+		Scope: FreeScope,
+		Index: len(s.FreeSymbols) - 1,
+	}
+	s.store[original.Name] = symbol
+	return symbol
+}
+
+// DefineFunctionName makes the name of the function being compiled
+// resolvable inside its own body, e.g. `fib` in let fib = fn(x) { fib(x) }.
+// Parameters and locals of the same name shadow it since they are defined later.
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	symbol := Symbol{
+		Name:  name,
+		Scope: FunctionScope,
+		Index: 0,
+	}
+	s.store[name] = symbol
+	return symbol
+}
+
 func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := s.store[name]
 	if !ok && s.Outer != nil {
 		obj, ok = s.Outer.Resolve(name)
-		return obj, ok
+		if !ok {
+			return obj, ok
+		}
+		if obj.Scope == GlobalScope {
+			return obj, ok
+		}
+		// a local of an enclosing function is captured as a free variable
+		return s.DefineFree(obj), true
 	}
 	return obj, ok
 }
@@ -146,3 +146,124 @@ func TestResolveNestedLocal(t *testing.T) {
 
 	}
 }
+
+func TestResolveFree(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+	global.Define("b")
+
+	firstLocal := NewEnclosedSymbolTable(global)
+	firstLocal.Define("c")
+	firstLocal.Define("d")
+
+	secondLocal := NewEnclosedSymbolTable(firstLocal)
+	secondLocal.Define("e")
+	secondLocal.Define("f")
+
+	thirdLocal := NewEnclosedSymbolTable(secondLocal)
+	thirdLocal.Define("g")
+
+	tests := []struct {
+		table               *SymbolTable
+		expectedSymbols     []Symbol
+		expectedFreeSymbols []Symbol
+	}{
+		{
+			thirdLocal,
+			[]Symbol{
+				Symbol{Name: "a", Scope: GlobalScope, Index: 0},
+				Symbol{Name: "c", Scope: FreeScope, Index: 0},
+				Symbol{Name: "e", Scope: FreeScope, Index: 1},
+				Symbol{Name: "g", Scope: LocalScope, Index: 0},
+			},
+			[]Symbol{
+				Symbol{Name: "c", Scope: FreeScope, Index: 0},
+				Symbol{Name: "e", Scope: LocalScope, Index: 0},
+			},
+		},
+		{
+			// "c" was resolved two levels up, so the table in between
+			// records it as one of its own free variables
+			secondLocal,
+			[]Symbol{
+				Symbol{Name: "c", Scope: FreeScope, Index: 0},
+				Symbol{Name: "e", Scope: LocalScope, Index: 0},
+			},
+			[]Symbol{
+				Symbol{Name: "c", Scope: LocalScope, Index: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, sym := range tt.expectedSymbols {
+			result, ok := tt.table.Resolve(sym.Name)
+			if !ok {
+				t.Errorf("name %s not resolvable", sym.Name)
+				continue
+			}
+			if result != sym {
+				t.Errorf("expected %s to resolve to %+v, got=%+v", sym.Name, sym, result)
+			}
+		}
+
+		if len(tt.table.FreeSymbols) != len(tt.expectedFreeSymbols) {
+			t.Errorf("wrong number of free symbols. got=%d, want=%d",
+				len(tt.table.FreeSymbols), len(tt.expectedFreeSymbols))
+			continue
+		}
+		for i, sym := range tt.expectedFreeSymbols {
+			result := tt.table.FreeSymbols[i]
+			if result != sym {
+				t.Errorf("wrong free symbol. got=%+v, want=%+v", result, sym)
+			}
+		}
+	}
+}
+
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("a")
+
+	local := NewEnclosedSymbolTable(global)
+	local.DefineFunctionName("fib")
+
+	expected := Symbol{Name: "fib", Scope: FunctionScope, Index: 0}
+
+	result, ok := local.Resolve("fib")
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestShadowingFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("fib")
+
+	local := NewEnclosedSymbolTable(global)
+	local.DefineFunctionName("fib")
+
+	// the function name shadows the global of the same name
+	expected := Symbol{Name: "fib", Scope: FunctionScope, Index: 0}
+	result, ok := local.Resolve("fib")
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+
+	// a parameter or local of the same name shadows the function name
+	local.Define("fib")
+	expected = Symbol{Name: "fib", Scope: LocalScope, Index: 0}
+	result, ok = local.Resolve("fib")
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
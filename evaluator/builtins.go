@@ -21,10 +21,13 @@ var builtins = map[string]*object.Builtin{
 				return &object.Integer{
 					Value: int64(len(arg.Value)),
 				}
+			case *object.Hash:
+				return &object.Integer{
+					Value: int64(len(arg.Pairs)),
+				}
 			default:
 				return newError("argument to `len` not supported, got %s", args[0].Type())
 			}
-			return NULL
 		},
 	},
 	"first": &object.Builtin{
@@ -102,6 +105,82 @@ var builtins = map[string]*object.Builtin{
 			}
 		},
 	},
+	"reverse": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Array:
+				length := len(arg.Elements)
+				newElements := make([]object.Object, length, length)
+				for i, el := range arg.Elements {
+					newElements[length-1-i] = el
+				}
+				return &object.Array{
+					Elements: newElements,
+				}
+			case *object.String:
+				// reverse runes rather than bytes so multibyte characters stay intact
+				runes := []rune(arg.Value)
+				for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+					runes[i], runes[j] = runes[j], runes[i]
+				}
+				return &object.String{
+					Value: string(runes),
+				}
+			default:
+				return newError("argument to `reverse` must be ARRAY or STRING, got %s", args[0].Type())
+			}
+		},
+	},
+	"enumerate": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			if args[0].Type() != object.ARRAY_OBJ {
+				return newError("argument to `enumerate` must be ARRAY, got %s", args[0].Type())
+			}
+
+			arr := args[0].(*object.Array)
+			pairs := make([]object.Object, len(arr.Elements))
+			for i, el := range arr.Elements {
+				pairs[i] = &object.Array{
+					Elements: []object.Object{&object.Integer{Value: int64(i)}, el},
+				}
+			}
+			return &object.Array{Elements: pairs}
+		},
+	},
+	"assertEq": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+			actual, expected := args[0], args[1]
+			if object.Equal(actual, expected) {
+				return NULL
+			}
+
+			msg := "assertEq failed"
+			if len(args) == 3 {
+				label, ok := args[2].(*object.String)
+				if !ok {
+					return newError("message to `assertEq` must be STRING, got %s", args[2].Type())
+				}
+				msg += ": " + label.Value
+			}
+			return newError("%s\n  expected: %s\n  actual:   %s",
+				msg, expected.Inspect(), actual.Inspect())
+		},
+	},
+	"isInt":    typePredicate(object.INTEGER_OBJ),
+	"isString": typePredicate(object.STRING_OBJ),
+	"isArray":  typePredicate(object.ARRAY_OBJ),
+	"isHash":   typePredicate(object.HASH_OBJ),
+	"isFn":     typePredicate(object.FUNCTION_OBJ, object.BUILTIN_OBJ),
+	"isNull":   typePredicate(object.NULL_OBJ),
 	"puts": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
@@ -111,3 +190,21 @@ var builtins = map[string]*object.Builtin{
 		},
 	},
 }
+
+// typePredicate builds an is<Type> builtin that reports whether its single
+// argument has one of the given types.
+func typePredicate(types ...object.ObjectType) *object.Builtin {
+	return &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			for _, t := range types {
+				if args[0].Type() == t {
+					return TRUE
+				}
+			}
+			return FALSE
+		},
+	}
+}
@@ -11,6 +11,9 @@ var (
 	NULL  = &object.Null{}
 	TRUE  = &object.Boolean{Value: true}
 	FALSE = &object.Boolean{Value: false}
+
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
@@ -29,9 +32,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
+	case *ast.NullLiteral:
+		return NULL
 	case *ast.ReturnStatement:
 		val := Eval(node.ReturnValue, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
 		return &object.ReturnValue{
@@ -39,30 +44,76 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 		right := Eval(node.Right, env)
-		if isError(right) {
+		if isAbrupt(right) {
 			return right
 		}
 		return evalInfixExpression(node.Operator, left, right)
+	case *ast.ComparisonChain:
+		return evalComparisonChain(node, env)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
+	case *ast.TernaryExpression:
+		condition := Eval(node.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return Eval(node.Consequence, env)
+		}
+		return Eval(node.Alternative, env)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
+	case *ast.BlockExpression:
+		// like an if block, the statements run in the enclosing environment.
+		// A return, break or continue inside is passed up through isAbrupt
+		// by whatever consumes the value
+		result := evalBlockStatement(node.Block, env)
+		if result == nil {
+			return NULL
+		}
+		return result
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
-		if isError(val) {
+		if isAbrupt(val) {
 			return val
 		}
 		env.Set(node.Name.Value, val)
+		traceBinding(env, node.Name.Value, val)
+	case *ast.MultiLetStatement:
+		// evaluate every value before binding any name
+		vals := evalExpressions(node.Values, env)
+		if len(vals) == 1 && isAbrupt(vals[0]) {
+			return vals[0]
+		}
+		for i, name := range node.Names {
+			env.Set(name.Value, vals[i])
+			traceBinding(env, name.Value, vals[i])
+		}
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+	case *ast.PostfixExpression:
+		return evalPostfixExpression(node, env)
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 	case *ast.FunctionLiteral:
@@ -76,17 +127,19 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return quote(node.Arguments[0], env)
 		}
 		function := Eval(node.Function, env)
-		if isError(function) {
+		if isAbrupt(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		args := evalArguments(node.Arguments, env)
+		if len(args) == 1 && isAbrupt(args[0]) {
 			return args[0]
 		}
 		return applyFunction(function, args)
+	case *ast.SpreadExpression:
+		return newError("spread operator ... is only allowed in call arguments")
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isAbrupt(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{
@@ -94,11 +147,11 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 	case *ast.IndexExpression:
 		left := Eval(node.Left, env)
-		if isError(left) {
+		if isAbrupt(left) {
 			return left
 		}
 		index := Eval(node.Index, env)
-		if isError(index) {
+		if isAbrupt(index) {
 			return index
 		}
 		return evalIndexExpression(left, index)
@@ -118,6 +171,8 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 			return result.Value
 		case *object.Error:
 			return result
+		case *object.Break, *object.Continue:
+			return newError("%s outside loop", result.Inspect())
 		}
 	}
 	return result
@@ -127,13 +182,116 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 	var result object.Object
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
+		if isAbrupt(result) {
+			return result
+		}
+	}
+	return result
+}
+
+// evalWhileStatement runs the body in the enclosing environment, like an
+// if block, until the condition is no longer truthy. A return or an error
+// inside the body stops the loop and is passed up unchanged; break stops it
+// and continue moves on to the next check of the condition.
+func evalWhileStatement(ws *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isAbrupt(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(ws.Body, env)
 		if result != nil {
-			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
 				return result
+			case object.BREAK_OBJ:
+				return NULL
 			}
 		}
 	}
+}
+
+// evalForStatement behaves like a while loop with its init run once up
+// front and its post run after every pass of the body. A missing condition
+// loops until the body returns. The loop shares the enclosing environment.
+func evalForStatement(fs *ast.ForStatement, env *object.Environment) object.Object {
+	if fs.Init != nil {
+		init := Eval(fs.Init, env)
+		if isAbrupt(init) {
+			return init
+		}
+	}
+
+	for {
+		if fs.Condition != nil {
+			condition := Eval(fs.Condition, env)
+			if isAbrupt(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return NULL
+			}
+		}
+
+		// continue falls through to the post statement
+		result := Eval(fs.Body, env)
+		if result != nil {
+			switch result.Type() {
+			case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+				return result
+			case object.BREAK_OBJ:
+				return NULL
+			}
+		}
+
+		if fs.Post != nil {
+			post := Eval(fs.Post, env)
+			if isAbrupt(post) {
+				return post
+			}
+		}
+	}
+}
+
+// evalLogicalExpression evaluates `&&` and `||` with short-circuiting.
+// Like `or`/`and` in Python, the result is the operand that decided the
+// outcome rather than a strict boolean: "a" && "b" is "b". The decision uses
+// the same truthiness as `if`, so 0 || 5 is 5.
+func evalLogicalExpression(node *ast.InfixExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isAbrupt(left) {
+		return left
+	}
+	if isTruthy(left) == (node.Operator == "||") {
+		return left
+	}
+	return Eval(node.Right, env)
+}
+
+// evalComparisonChain evaluates a < b < c as (a < b) && (b < c), except
+// that every operand is evaluated at most once, left to right. It stops at
+// the first comparison that fails.
+func evalComparisonChain(node *ast.ComparisonChain, env *object.Environment) object.Object {
+	left := Eval(node.Operands[0], env)
+	if isAbrupt(left) {
+		return left
+	}
+	var result object.Object
+	for i, operator := range node.Operators {
+		right := Eval(node.Operands[i+1], env)
+		if isAbrupt(right) {
+			return right
+		}
+		result = evalInfixExpression(operator, left, right)
+		if isAbrupt(result) || !isTruthy(result) {
+			return result
+		}
+		left = right
+	}
 	return result
 }
 
@@ -143,22 +301,15 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalBangOperatorExpression(right)
 	case "-":
 		return evalMinusPrefixOperatorExpression(right)
+	case "~":
+		return evalTildePrefixOperatorExpression(right)
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
 func evalBangOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-	case FALSE:
-		return TRUE
-	case NULL:
-		return TRUE
-	default:
-		return FALSE
-	}
+	return nativeBoolToBooleanObject(!isTruthy(right))
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
@@ -169,6 +320,14 @@ func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
 	return &object.Integer{Value: -value}
 }
 
+func evalTildePrefixOperatorExpression(right object.Object) object.Object {
+	if right.Type() != object.INTEGER_OBJ {
+		return newError("unknown operator: ~%s", right.Type())
+	}
+	value := right.(*object.Integer).Value
+	return &object.Integer{Value: ^value}
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -218,6 +377,25 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
 		return &object.Integer{Value: leftVal / rightVal}
+	case "**":
+		if rightVal < 0 {
+			return newError("negative exponent: %d", rightVal)
+		}
+		return &object.Integer{Value: object.IntegerPow(leftVal, rightVal)}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<<", ">>":
+		if rightVal < 0 {
+			return newError("negative shift count: %d", rightVal)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftVal << rightVal}
+		}
+		return &object.Integer{Value: leftVal >> rightVal}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
@@ -234,7 +412,7 @@ func evalIntegerInfixExpression(operator string, left, right object.Object) obje
 
 func evalIfExpression(ie *ast.IfExpression, env *object.Environment) object.Object {
 	condition := Eval(ie.Condition, env)
-	if isError(condition) {
+	if isAbrupt(condition) {
 		return condition
 	}
 
@@ -254,7 +432,52 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 	if builtin, ok := builtins[node.Value]; ok {
 		return builtin
 	}
-	return newError("identifier not found: " + node.Value)
+	return newError("identifier not found: %s", node.Value)
+}
+
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	if node.Name == nil {
+		return newError("invalid assignment target")
+	}
+	val := Eval(node.Value, env)
+	if isAbrupt(val) {
+		return val
+	}
+	if _, ok := env.Assign(node.Name.Value, val); !ok {
+		return newError("identifier not found: %s", node.Name.Value)
+	}
+	traceBinding(env, node.Name.Value, val)
+	return val
+}
+
+// evalPostfixExpression updates the integer bound to the identifier and
+// returns the value it held before the update
+func evalPostfixExpression(node *ast.PostfixExpression, env *object.Environment) object.Object {
+	ident, ok := node.Left.(*ast.Identifier)
+	if !ok {
+		return newError("invalid assignment target")
+	}
+	old := evalIdentifier(ident, env)
+	if isAbrupt(old) {
+		return old
+	}
+	if old.Type() != object.INTEGER_OBJ {
+		return newError("unknown operator: %s%s", old.Type(), node.Operator)
+	}
+	operator := node.Operator[:1]
+	val := evalIntegerInfixExpression(operator, old, &object.Integer{Value: 1})
+	if _, ok := env.Assign(ident.Value, val); !ok {
+		return newError("identifier not found: %s", ident.Value)
+	}
+	traceBinding(env, ident.Value, val)
+	return old
+}
+
+// traceBinding writes "name = value" when the environment has a tracer set
+func traceBinding(env *object.Environment, name string, val object.Object) {
+	if w := env.Tracer(); w != nil {
+		fmt.Fprintf(w, "%s = %s\n", name, val.Inspect())
+	}
 }
 
 func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
@@ -262,7 +485,7 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 
 	for _, e := range exps {
 		evaluated := Eval(e, env)
-		if isError(evaluated) {
+		if isAbrupt(evaluated) {
 			return []object.Object{evaluated}
 		}
 		result = append(result, evaluated)
@@ -270,6 +493,35 @@ func evalExpressions(exps []ast.Expression, env *object.Environment) []object.Ob
 	return result
 }
 
+// evalArguments evaluates call arguments like evalExpressions, expanding
+// a spread argument `...xs` into the elements of the array xs
+func evalArguments(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, e := range exps {
+		spread, ok := e.(*ast.SpreadExpression)
+		if !ok {
+			evaluated := Eval(e, env)
+			if isAbrupt(evaluated) {
+				return []object.Object{evaluated}
+			}
+			result = append(result, evaluated)
+			continue
+		}
+
+		evaluated := Eval(spread.Value, env)
+		if isAbrupt(evaluated) {
+			return []object.Object{evaluated}
+		}
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			return []object.Object{newError("spread argument must be ARRAY, got %s", evaluated.Type())}
+		}
+		result = append(result, array.Elements...)
+	}
+	return result
+}
+
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
@@ -309,9 +561,9 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.HashKey]object.HashPair)
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
-		if isError(key) {
+	for _, pairNode := range node.Pairs {
+		key := Eval(pairNode.Key, env)
+		if isAbrupt(key) {
 			return key
 		}
 
@@ -320,8 +572,8 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 			return newError("unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
-		if isError(value) {
+		value := Eval(pairNode.Value, env)
+		if isAbrupt(value) {
 			return value
 		}
 
@@ -336,7 +588,12 @@ func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Obje
 	}
 }
 
+// isTruthy decides conditions, `!`, `? :`, `&&` and `||` alike: false, null
+// and the integer 0 are falsy, everything else is truthy.
 func isTruthy(obj object.Object) bool {
+	if integer, ok := obj.(*object.Integer); ok {
+		return integer.Value != 0
+	}
 	switch obj {
 	case NULL:
 		return false
@@ -355,9 +612,17 @@ func newError(format string, a ...interface{}) *object.Error {
 	}
 }
 
-func isError(obj object.Object) bool {
-	if obj != nil {
-		return obj.Type() == object.ERROR_OBJ
+// isAbrupt reports whether evaluating a node ended early: with an error, or
+// with a return, break or continue coming out of a block expression such as
+// `{ return 7; 1 }`. Such a result is passed up unchanged instead of being
+// used as a value, so it reaches the function or loop it belongs to.
+func isAbrupt(obj object.Object) bool {
+	if obj == nil {
+		return false
+	}
+	switch obj.Type() {
+	case object.ERROR_OBJ, object.RETURN_VALUE_OBJ, object.BREAK_OBJ, object.CONTINUE_OBJ:
+		return true
 	}
 	return false
 }
@@ -385,8 +650,12 @@ func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Enviro
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
-	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue.Value
+	switch obj := obj.(type) {
+	case *object.ReturnValue:
+		return obj.Value
+	case *object.Break, *object.Continue:
+		// a loop cannot be left from inside a function called by its body
+		return newError("%s outside loop", obj.Inspect())
 	}
 	return obj
 }
@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/tamurayoshiya/monkey/lexer"
@@ -20,12 +21,24 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"5", 5},
 		{"10", 10},
 		{"-5", -5},
+		{"--5", 5},
+		{"5--3", 8},
 		{"5 + 5 + 5 + 5 - 10", 10},
 		{"2 * 2 * 2 * 2 * 2", 32},
 		{"-50 + 100 + -50", 0},
 		{"5 * 2 + 10", 20},
 		{"5 + 2 * 10", 25},
 		{"20 + 2 * -10", 0},
+		{"~5", -6},
+		{"~~5", 5},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"1 << 4", 16},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"-16 >> 2", -4},
 		{"50 / 2 * 2 + 10", 60},
 		{"2 * (5 + 10)", 30},
 		{"3 * 3 * 3 + 10", 37},
@@ -108,6 +121,12 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 < 2 < 3", true},
+		{"1 < 3 < 2", false},
+		{"3 > 2 > 1", true},
+		{"1 == 1 == 1", true},
+		{"1 < 2 < 3 < 4", true},
+		{"1 != 2 == 3", false},
 	}
 
 	for _, tt := range tests {
@@ -116,6 +135,23 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestComparisonChainEvaluatesOperandsOnce(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; 0 < i++ < 5; i", 1},
+		{"let n = 0; let f = fn() { n += 1; 2 }; 1 < f() < 3; n", 1},
+		{"let n = 0; let f = fn() { n += 1; 2 }; f() < f() < f(); n", 2},
+		{"let n = 0; let f = fn() { n += 1; 0 }; 1 < 0 < f(); n", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, tt.expected)
+	}
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -181,6 +217,39 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestBlockExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"{ let x = 1; x + 1 }", 2},
+		{"let y = { let a = 2; a * 3 }; y", 6},
+		{"{ 1 } + { 2 }", 3},
+		{"{ let x = 1; }", nil},
+		{"{ ; }", nil},
+		{"let f = fn() { { return 5; }; 10 }; f()", 5},
+		{"{ let z = 4; z }; z", 4},
+		// return, break and continue leave the enclosing function or loop,
+		// not just the block expression
+		{"fn(){ let y = { return 7; 1 }; 9 }()", 7},
+		{"fn(){ 1 + { return 7; 1 } }()", 7},
+		{"if ({ return 7; true }) { 1 } else { 2 }", 7},
+		{"let i = 0; while (i < 5) { i += 1; let x = { break; 1 }; }; i", 1},
+		{"let i = 0; let n = 0; while (i < 5) { i += 1; let x = { continue; 1 }; n += 1; }; n", 0},
+		{"fn(){ [1, { return 7; 2 }] }()", 7},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		integer, ok := tt.expected.(int)
+		if ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func testNullObject(t *testing.T, obj object.Object) bool {
 	if obj != NULL {
 		t.Errorf("object is not NULL. got=%T (%+v)", obj, obj)
@@ -236,6 +305,14 @@ func TestErrorHandling(t *testing.T) {
 			"5 + true; 5;",
 			"type mismatch: INTEGER + BOOLEAN",
 		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"2 ** -1",
+			"negative exponent: -1",
+		},
 		{
 			"-true",
 			"unknown operator: -BOOLEAN",
@@ -314,6 +391,49 @@ func TestLetStatements(t *testing.T) {
 
 // Test function object
 
+func TestMultipleLetDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 1, b = 2; a;", 1},
+		{"let a = 1, b = 2; b;", 2},
+		{"let a = 5, b = a * 2; a + b;", 15},
+		{"let f = fn() { let x = 3, y = x + 1; x * y }; f();", 12},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestMultiNameLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a, b = 1, 2; a;", 1},
+		{"let a, b = 1, 2; b;", 2},
+		{"let a, b = 3, 4; a * 10 + b;", 34},
+		// every value is evaluated before any name is bound
+		{"let a = 1; let b = 2; let a, b = b, a; a * 10 + b;", 21},
+		{"let f = fn() { let x, y = 3, 4; x * y }; f();", 12},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("let a, b = 1, c;")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("expected an error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: c" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -402,7 +522,13 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len([])`, 0},
+		{`len([1, 2, 3])`, 3},
+		{`len({})`, 0},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`len({"a": 1, "a": 2})`, 1},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
+		{`len(fn(x) { x })`, "argument to `len` not supported, got FUNCTION"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 	}
 	for _, tt := range tests {
@@ -568,6 +694,14 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{"foo": 5}["bar"]`,
 			nil,
 		},
+		{
+			`let user = {"name": {"first": 5}}; user.name.first`,
+			5,
+		},
+		{
+			`{"foo": 5}.bar`,
+			nil,
+		},
 		{
 			`let key = "foo"; {"foo": 5}[key]`,
 			5,
@@ -600,3 +734,523 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+// ------------------------------------------------------------------------
+
+// Test Trace Mode
+
+func TestTraceBindings(t *testing.T) {
+	input := `
+	let a = 1;
+	let b = a + 1;
+	let s = "hi";
+	if (b > a) { let c = [a, b]; }
+	let inc = fn(x) { let y = x + 1; y };
+	inc(b);
+	a = 10;
+	`
+	expected := "a = 1\n" +
+		"b = 2\n" +
+		"s = hi\n" +
+		"c = [1, 2]\n" +
+		"inc = fn(x) {\nlet y = (x + 1);y\n}\n" +
+		"y = 3\n" +
+		"a = 10\n"
+
+	var out bytes.Buffer
+	env := object.NewEnvironment()
+	env.SetTracer(&out)
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	Eval(p.ParseProgram(), env)
+
+	if out.String() != expected {
+		t.Errorf("wrong trace output.\nwant=%q\ngot =%q", expected, out.String())
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let x = 1; x = 5; x;", 5},
+		{"let x = 1; x = 5;", 5},
+		{"let x = 1; let y = 2; x = y = 3; x + y;", 6},
+		{"let c = 0; let inc = fn() { c = c + 1 }; inc(); inc(); c;", 2},
+		{"let f = fn(x) { x = x * 2; x }; f(4);", 8},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x;", 6},
+		{"let s = 0; let add = fn(n) { s += n }; add(2); add(3); s;", 5},
+		{"x = 1;", "identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestPostfixExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 5; i++;", 5},
+		{"let i = 5; i++; i;", 6},
+		{"let i = 5; i--; i;", 4},
+		{"let i = 0; let j = i++ + i++; j;", 1},
+		{"let i = 0; while (i < 3) { i++; } i;", 3},
+		{`let s = "a"; s++;`, "unknown operator: STRING++"},
+		{"x++;", "identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestSpreadArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(a, b, c) { a + b + c }; add(...[1, 2, 3]);", 6},
+		{"let add = fn(a, b, c) { a + b + c }; let xs = [2, 3]; add(1, ...xs);", 6},
+		{"let add = fn(a, b) { a + b }; add(...[1], ...[2]);", 3},
+		{"len(...[[1, 2, 3]]);", 3},
+		{"let f = fn(a) { a }; f(...5);", "spread argument must be ARRAY, got INTEGER"},
+		{"let f = fn(a) { a }; f(...missing);", "identifier not found: missing"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestWhileStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 10) { i = i + 1; } i;", 10},
+		{"let i = 0; let sum = 0; while (i < 5) { i += 1; sum += i; } sum;", 15},
+		{"let i = 0; while (false) { i = 1; } i;", 0},
+		{"let f = fn() { let i = 0; while (true) { i += 1; if (i > 3) { return i; } } }; f();", 4},
+		{"while (true) { x; }", "identifier not found: x"},
+		{"while (x) { 1 }", "identifier not found: x"},
+		{"while (false) { 1 }", nil},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestForStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (let i = 1; i < 5; i += 1) { sum += i; } sum;", 10},
+		{"let sum = 0; for (let i = 0; i < 5; i += 1) { sum += i; } i;", 5},
+		{"let n = 0; for (; n < 3;) { n += 1; } n;", 3},
+		{"let f = fn() { let i = 0; for (;;) { i += 1; if (i > 2) { return i; } } }; f();", 3},
+		{"for (let i = 0; i < 3; i += 1) { }", nil},
+		{"for (let i = 0; x; i += 1) { }", "identifier not found: x"},
+		{"for (let i = 0; i < 3; y += 1) { }", "identifier not found: y"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestBreakAndContinue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (true) { i += 1; if (i > 4) { break; } } i;", 5},
+		{"let i = 0; let sum = 0; while (i < 6) { i += 1; if (i == 3) { continue; } sum += i; } sum;", 18},
+		{"let sum = 0; for (let i = 0; i < 6; i += 1) { if (i == 3) { continue; } sum += i; } sum;", 12},
+		{"let sum = 0; for (let i = 0; i < 6; i += 1) { if (i == 3) { break; } sum += i; } sum;", 3},
+		// 内側のループだけを抜ける
+		{`let n = 0;
+		  for (let i = 0; i < 3; i += 1) {
+		    for (let j = 0; j < 3; j += 1) { if (j == 1) { break; } n += 1; }
+		  }
+		  n;`, 3},
+		{"while (true) { break; }", nil},
+		{"break;", "break outside loop"},
+		{"if (true) { continue; }", "continue outside loop"},
+		{"let f = fn() { break; }; while (true) { f(); }", "break outside loop"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestNullLiteral(t *testing.T) {
+	tests := []string{
+		"null",
+		"let x = null; x",
+		"fn() { null }()",
+	}
+	for _, input := range tests {
+		testNullObject(t, testEval(input))
+	}
+
+	testIntegerObject(t, testEval("null || 5"), 5)
+	testBooleanObject(t, testEval("!null"), true)
+	testIntegerObject(t, testEval("if (null) { 1 } else { 2 }"), 2)
+}
+
+func TestTernaryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 > 2 ? 10 : 20", 20},
+		{"2 > 1 ? 10 : 20", 10},
+		{"null ? 1 : 2", 2},
+		{"0 ? 1 : 2", 2},
+		{"let a = 5; let b = 3; a > b ? a : b", 5},
+		{"false ? 1 : true ? 2 : 3", 2},
+		// only the chosen branch is evaluated
+		{"true ? 1 : x", 1},
+		{"false ? x : 2", 2},
+		{"x ? 1 : 2", "identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		// the operand that decided the outcome is the result
+		{`"a" && "b"`, "b"},
+		{"false || 5", 5},
+		{"false && 5", false},
+		{"3 || 5", 3},
+		// 0 is falsy, as in if conditions
+		{"0 || 5", 5},
+		{"0 && 5", 0},
+		{"1 && 0", 0},
+		{"0 || 0", 0},
+		{"-1 || 5", -1},
+		{"if (0) { 1 } else { 2 }", 2},
+		{"!0", true},
+		{"let x = if (false) { 1 } || 7; x", 7},
+		// the right operand is only evaluated when needed
+		{"false && x", false},
+		{"true || x", true},
+		{"true && x", "identifier not found: x"},
+		{"let n = 0; let inc = fn() { n += 1; true }; false && inc(); true || inc(); n", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			switch obj := evaluated.(type) {
+			case *object.String:
+				if obj.Value != expected {
+					t.Errorf("String has wrong value. want=%q, got=%q", expected, obj.Value)
+				}
+			case *object.Error:
+				if obj.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, obj.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
+
+func TestHashComputedKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let h = {1 + 1: "x"}; h[2]`, "x"},
+		{`let a = 1; let h = {(a + 1): "x"}; h[2]`, "x"},
+		{`let h = {"a" + "b": "x"}; h["ab"]`, "x"},
+		{`let k = fn() { true }; let h = {k(): "x"}; h[true]`, "x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. got=%q, want=%q", str.Value, tt.expected)
+		}
+	}
+}
+
+func TestTypePredicateBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"isInt(1)", true},
+		{`isInt("1")`, false},
+		{`isString("a")`, true},
+		{"isString([])", false},
+		{"isArray([1, 2])", true},
+		{"isArray({})", false},
+		{`isHash({"a": 1})`, true},
+		{"isHash([])", false},
+		{"isFn(fn(x) { x })", true},
+		{"isFn(len)", true},
+		{"isFn(1)", false},
+		{"isNull(null)", true},
+		{"isNull(if (false) { 1 })", true},
+		{"isNull(0)", false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("isInt(1, 2)")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "wrong number of arguments. got=2, want=1" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestEnumerateBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`enumerate(["a", "b"])`, "[[0, a], [1, b]]"},
+		{`enumerate([])`, "[]"},
+		{`enumerate([true, [1]])`, "[[0, true], [1, [1]]]"},
+		{`enumerate("ab")`, "ERROR: argument to `enumerate` must be ARRAY, got STRING"},
+		{`enumerate([1], [2])`, "ERROR: wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %s. want=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	pairs := testEval(`enumerate(["a", "b"])`).(*object.Array)
+	second := pairs.Elements[1].(*object.Array)
+	testIntegerObject(t, second.Elements[0], 1)
+	if str, ok := second.Elements[1].(*object.String); !ok || str.Value != "b" {
+		t.Errorf("second pair value is not \"b\". got=%+v", second.Elements[1])
+	}
+}
+
+func TestAssertEqBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`assertEq(1 + 1, 2)`, nil},
+		{`assertEq([1, [2, 3]], [1, [2, 3]])`, nil},
+		{`assertEq({"a": 1}, {"a": 1}, "hashes")`, nil},
+		{`assertEq([1, 2], [1, 3])`, "assertEq failed\n  expected: [1, 3]\n  actual:   [1, 2]"},
+		{`assertEq("a", "b", "strings")`, "assertEq failed: strings\n  expected: b\n  actual:   a"},
+		{`assertEq(1, 2, 3)`, "message to `assertEq` must be STRING, got INTEGER"},
+		{`assertEq(1)`, "wrong number of arguments. got=1, want=2 or 3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestReverseBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`reverse([1, 2, 3])`, []int64{3, 2, 1}},
+		{`reverse([])`, []int64{}},
+		{`let a = [1, 2]; reverse(a); a`, []int64{1, 2}},
+		{`reverse("abc")`, "cba"},
+		{`reverse("")`, ""},
+		{`reverse("日本語")`, "語本日"},
+		{`reverse(1)`, "argument to `reverse` must be ARRAY or STRING, got INTEGER"},
+		{`reverse([1], [2])`, "wrong number of arguments. got=2, want=1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case []int64:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if len(array.Elements) != len(expected) {
+				t.Errorf("wrong num of elements. want=%d, got=%d", len(expected), len(array.Elements))
+				continue
+			}
+			for i, expectedElem := range expected {
+				testIntegerObject(t, array.Elements[i], expectedElem)
+			}
+		case string:
+			switch result := evaluated.(type) {
+			case *object.String:
+				if result.Value != expected {
+					t.Errorf("String has wrong value. got=%q, want=%q", result.Value, expected)
+				}
+			case *object.Error:
+				if result.Message != expected {
+					t.Errorf("wrong error message. expected=%q, got=%q", expected, result.Message)
+				}
+			default:
+				t.Errorf("object is not String or Error. got=%T (%+v)", evaluated, evaluated)
+			}
+		}
+	}
+}
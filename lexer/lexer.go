@@ -1,24 +1,131 @@
 package lexer
 
-import "github.com/tamurayoshiya/monkey/token"
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tamurayoshiya/monkey/token"
+)
 
 type Lexer struct {
 	input        string
-	position     int  // 入力における現在の位置（現在の文字を指し示す）
+	position     int  // 入力における現在の位置（現在の文字を指し示すバイトオフセット）
 	readPosition int  // これから読み込む位置（現在の文字の次）
-	ch           byte // 現在検査中の文字
+	ch           rune // 現在検査中の文字
+	line         int  // 現在の文字の行(1始まり)
+	column       int  // 現在の文字の列(1始まり、文字単位)
+
+	keywords map[string]token.TokenType // 組み込みのキーワードに追加するキーワード
+	symbols  map[rune]token.TokenType   // 組み込みで使われていない記号に割り当てるトークンタイプ
+	tabWidth int                        // タブ1文字で進む列数
+	prevType token.TokenType            // 直前に返したトークンのタイプ
+}
+
+// 字句解析器の動作を変えるオプション。ゼロ値はNewと同じ動作になる
+type Options struct {
+	// タブ1文字で進む列数。0以下なら1
+	// エディタの表示に合わせてトークンの列を報告するためのもの
+	TabWidth int
+
+	// 組み込みのキーワードに加えて認識する独自のキーワード
+	// 組み込みのキーワードと同じ単語を渡した場合は、渡したトークンタイプが優先される
+	Keywords map[string]token.TokenType
+
+	// 組み込みで使われていない1文字の記号(@など)に割り当てる独自のトークンタイプ
+	// 組み込みの記号や識別子・数値に使われる文字を渡しても無視される
+	Symbols map[rune]token.TokenType
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewWithOptions(input, Options{})
+}
+
+func NewWithOptions(input string, options Options) *Lexer {
+	l := &Lexer{
+		input:    input,
+		line:     1,
+		keywords: options.Keywords,
+		symbols:  options.Symbols,
+		tabWidth: options.TabWidth,
+	}
+	if l.tabWidth <= 0 {
+		l.tabWidth = 1
+	}
 	l.readChar()
 	return l
 }
 
+// 識別子がキーワードならそのトークンタイプを、そうでなければIDENTを返す
+func (l *Lexer) lookupIdent(ident string) token.TokenType {
+	if tok, ok := l.keywords[ident]; ok {
+		return tok
+	}
+	return token.LookupIdent(ident)
+}
+
+// 現在の文字(次のトークンの読み取りを始める位置)のバイトオフセット、行、列を返す
+// 行と列は1始まり。入力の末尾では最後の文字の次の位置を返す
+func (l *Lexer) Position() (offset, line, column int) {
+	offset = l.position
+	if offset > len(l.input) {
+		offset = len(l.input)
+	}
+	return offset, l.line, l.column
+}
+
+// 次のトークンを読み、その先頭の位置を記録して返す
 func (l *Lexer) NextToken() token.Token {
+	startLine := l.line
+	ok := l.skipWhitespaceAndComments()
+	line, column := l.line, l.column
+
 	var tok token.Token
+	if ok {
+		tok = l.readToken()
+	} else {
+		// 閉じられていないブロックコメントはEOFまで読み進めてILLEGALとする
+		for l.ch != 0 {
+			l.readChar()
+		}
+		tok = token.Token{Type: token.ILLEGAL, Literal: "/*"}
+	}
+	tok.Line = line
+	tok.Column = column
+	tok.LeadingNewlines = line - startLine
+	l.prevType = tok.Type
+	return tok
+}
+
+// 字句解析器をEOFまで読み進め、トークンの列を返す(最後のEOFトークンを含む)
+// デバッグ用。パーサーに渡している字句解析器ではなく、別に生成したものを渡すこと
+func Tokens(l *Lexer) []token.Token {
+	tokens := []token.Token{}
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// 入力を字句解析し、最後のEOFを含むトークンの列を返す
+// ILLEGALトークンがあれば、最初のものの位置をエラーとして返す(トークンの列はすべて返す)
+func Tokenize(input string) ([]token.Token, error) {
+	tokens := Tokens(New(input))
+	for _, tok := range tokens {
+		if tok.Type == token.ILLEGAL {
+			return tokens, fmt.Errorf("illegal token %q at line %d, column %d",
+				tok.Literal, tok.Line, tok.Column)
+		}
+	}
+	return tokens, nil
+}
 
-	l.skipWhitespace()
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -32,9 +139,19 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '+' {
+			tok = l.newDoubleCharToken('+', token.INC)
+		} else {
+			tok = l.newOperatorToken(token.PLUS, token.PLUS_EQ)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		// "--"をデクリメントとして扱うのは被演算子になりうる識別子・")"・"]"の直後だけ
+		// それ以外(--5 や 5--3)では"-"が2つ並んだものとして扱う
+		if l.peekChar() == '-' && isOperandEnd(l.prevType) {
+			tok = l.newDoubleCharToken('-', token.DEC)
+		} else {
+			tok = l.newOperatorToken(token.MINUS, token.MINUS_EQ)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -44,14 +161,46 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.BANG, l.ch)
 		}
+	case '~':
+		tok = newToken(token.TILDE, l.ch)
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		// 次の文字も"*"の場合"**"としてトークン化
+		if l.peekChar() == '*' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{Type: token.POW, Literal: literal}
+		} else {
+			tok = l.newOperatorToken(token.ASTERISK, token.ASTERISK_EQ)
+		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		tok = l.newOperatorToken(token.SLASH, token.SLASH_EQ)
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.newDoubleCharToken('&', token.AND)
+		} else {
+			tok = newToken(token.BIT_AND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.newDoubleCharToken('|', token.OR)
+		} else {
+			tok = newToken(token.BIT_OR, l.ch)
+		}
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		if l.peekChar() == '<' {
+			tok = l.newDoubleCharToken('<', token.SHL)
+		} else {
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		if l.peekChar() == '>' {
+			tok = l.newDoubleCharToken('>', token.SHR)
+		} else {
+			tok = newToken(token.GT, l.ch)
+		}
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case '(':
@@ -68,23 +217,53 @@ func (l *Lexer) NextToken() token.Token {
 		tok.Literal = ""
 		tok.Type = token.EOF
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		start := l.position
+		if str, ok := l.readString(); ok {
+			tok.Type = token.STRING
+			tok.Literal = str
+		} else {
+			// 閉じられていない文字列は、開始の"からEOFまでをILLEGALとする
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.input[start:]
+		}
+	case '`':
+		start := l.position
+		if str, ok := l.readRawString(); ok {
+			tok.Type = token.STRING
+			tok.Literal = str
+		} else {
+			tok.Type = token.ILLEGAL
+			tok.Literal = l.input[start:]
+		}
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
 		tok = newToken(token.RBRACKET, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
+	case '?':
+		tok = newToken(token.QUESTION, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.DOT, l.ch)
+		}
 	default:
+		// "_"の位置の規則は数値リテラルの中だけに適用する
+		// "_"で始まる "_1" は数値ではなく識別子になる
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
-			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Type = l.lookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Type = token.INT
 			tok.Literal = l.readNumber()
 			return tok
+		} else if t, ok := l.symbols[l.ch]; ok {
+			tok = newToken(t, l.ch)
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
 		}
@@ -93,15 +272,56 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-func (l *Lexer) readString() string {
-	position := l.position + 1
+// 文字列リテラルを読み、エスケープシーケンスを解釈した値を返す
+// 対応するのは \n, \t, \", \\ のみ。未知のエスケープ(\qなど)はバックスラッシュごとそのまま残す
+// 閉じる"がないままEOFに達した場合はfalseを返す
+func (l *Lexer) readString() (string, bool) {
+	var out []byte
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
+		if l.ch == '"' {
 			break
 		}
+		if l.ch == 0 {
+			return string(out), false
+		}
+		if l.ch == '\\' {
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				out = append(out, '\n')
+			case 't':
+				out = append(out, '\t')
+			case '"':
+				out = append(out, '"')
+			case '\\':
+				out = append(out, '\\')
+			case 0:
+				return string(out), false
+			default:
+				out = append(out, '\\')
+				out = append(out, string(l.ch)...)
+			}
+			continue
+		}
+		out = append(out, string(l.ch)...)
+	}
+	return string(out), true
+}
+
+// `で囲まれた生文字列リテラルを読む。エスケープは解釈せず、改行も含めてそのまま値とする
+// 閉じる`がないままEOFに達した場合はfalseを返す
+func (l *Lexer) readRawString() (string, bool) {
+	position := l.position + 1
+	for {
+		l.readChar()
+		if l.ch == '`' {
+			return l.input[position:l.position], true
+		}
+		if l.ch == 0 {
+			return l.input[position:l.position], false
+		}
 	}
-	return l.input[position:l.position]
 }
 
 func (l *Lexer) skipWhitespace() {
@@ -110,55 +330,192 @@ func (l *Lexer) skipWhitespace() {
 	}
 }
 
+// 空白とコメントを読み飛ばす。コメントはトークンを生成しない
+// 閉じられていないブロックコメントがある場合は、その先頭で止まってfalseを返す
+func (l *Lexer) skipWhitespaceAndComments() bool {
+	for {
+		l.skipWhitespace()
+		if l.ch == '/' && l.peekChar() == '/' {
+			l.skipLineComment()
+			continue
+		}
+		if l.ch == '/' && l.peekChar() == '*' {
+			if !l.skipBlockComment() {
+				return false
+			}
+			continue
+		}
+		return true
+	}
+}
+
+// `//` から行末(またはEOF)までを読み飛ばす。改行はskipWhitespaceで読む
+func (l *Lexer) skipLineComment() {
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// `/*` から `*/` までを読み飛ばす。途中の改行もreadCharで読み進める
+// 閉じられていない場合は何も読まずにfalseを返す
+func (l *Lexer) skipBlockComment() bool {
+	if !strings.Contains(l.input[l.position+2:], "*/") {
+		return false
+	}
+	l.readChar() // '/'
+	l.readChar() // '*'
+	for l.ch != 0 {
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.readChar()
+			l.readChar()
+			return true
+		}
+		l.readChar()
+	}
+	return false
+}
+
 // peek = 覗き見、 readCharに似ているが字句解析の位置は進めない
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
 }
 
 // 字句解析の位置を進める
 func (l *Lexer) readChar() {
+	// 改行を読み終えたら次の行の先頭へ
+	// タブの次の文字はtabWidth列進める
+	step := 1
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	} else if l.ch == '\t' {
+		step = l.tabWidth
+	}
+	// 入力はUTF-8として1文字(rune)ずつ読む
+	width := 1
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 	l.position = l.readPosition
-	l.readPosition++
+	l.readPosition += width
+	// 入力の末尾を越えて読み続けても列は進めない
+	if l.position <= len(l.input) {
+		l.column += step
+	}
 }
 
-// 字句解析の位置を非英字文字まで進める
+// 字句解析の位置を識別子の終わりまで進める
+// 先頭の文字は英字(Unicodeの文字を含む)か"_"で、2文字目以降は数字と結合文字も使える
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) || unicode.IsMark(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
 // 字句解析の位置を非数値まで進める
+// 0x(16進数)、0o(8進数)、0b(2進数)の接頭辞にも対応する
+// 値への変換はパーサーのstrconv.ParseInt(基数0)に任せる
 func (l *Lexer) readNumber() string {
 	position := l.position
-	for isDigit(l.ch) {
+	isValidDigit := isDigit
+	if l.ch == '0' {
+		if f := radixDigit(l.peekChar()); f != nil {
+			// 接頭辞の2文字を読み飛ばす
+			isValidDigit = f
+			l.readChar()
+			l.readChar()
+		}
+	}
+	// 区切り文字"_"の位置の検証はパーサーで行う
+	for isValidDigit(l.ch) || l.ch == '_' {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+// 直後の"--"をデクリメントとして扱うトークンタイプかどうか
+func isOperandEnd(t token.TokenType) bool {
+	return t == token.IDENT || t == token.RPAREN || t == token.RBRACKET
+}
+
+// 次の文字が"="の場合は複合代入演算子(+= など)として、それ以外は単独の演算子としてトークン化
+func (l *Lexer) newOperatorToken(single, compound token.TokenType) token.Token {
+	if l.peekChar() == '=' {
+		ch := l.ch
+		l.readChar()
+		return token.Token{Type: compound, Literal: string(ch) + string(l.ch)}
+	}
+	return newToken(single, l.ch)
+}
+
+// 同じ文字が2つ続く場合のみ演算子(&& など)としてトークン化し、1文字だけの場合はILLEGALとする
+func (l *Lexer) newDoubleCharToken(ch rune, t token.TokenType) token.Token {
+	if l.peekChar() == ch {
+		l.readChar()
+		return token.Token{Type: t, Literal: string(ch) + string(ch)}
+	}
+	return newToken(token.ILLEGAL, l.ch)
+}
+
+// 1バイト文字のリテラル
+// 区切り記号などのトークンごとに string(ch) で文字列を確保しないように、あらかじめ作っておく
+var asciiLiterals = func() (literals [utf8.RuneSelf]string) {
+	for i := range literals {
+		literals[i] = string(rune(i))
+	}
+	return literals
+}()
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
+	literal := ""
+	if ch >= 0 && ch < utf8.RuneSelf {
+		literal = asciiLiterals[ch]
+	} else {
+		literal = string(ch)
+	}
 	return token.Token{
 		Type:    tokenType,
-		Literal: string(ch),
+		Literal: literal,
 	}
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
+
+// 基数の接頭辞文字に対応する数字判定関数を返す。接頭辞でなければnil
+func radixDigit(prefix rune) func(rune) bool {
+	switch prefix {
+	case 'x', 'X':
+		return isHexDigit
+	case 'o', 'O':
+		return isOctalDigit
+	case 'b', 'B':
+		return isBinaryDigit
+	}
+	return nil
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func isOctalDigit(ch rune) bool {
+	return '0' <= ch && ch <= '7'
+}
+
+func isBinaryDigit(ch rune) bool {
+	return ch == '0' || ch == '1'
+}
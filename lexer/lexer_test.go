@@ -16,7 +16,7 @@ func TestNextToken(t *testing.T) {
 	};
 
 	let result = add(five, ten);
-	!-/*5;
+	!-/ *5;
 	5 < 10 > 5;
 
 	if (5 < 10) {
@@ -32,6 +32,14 @@ func TestNextToken(t *testing.T) {
 	[1, 2];
 	{"foo": "bar"}
 	macro(x, y) { x + y; };
+	2 ** 3;
+	x += 1; x -= 2; x *= 3; x /= 4;
+	a && b || c; & | ^
+	1 << 2 >> 3 < > 4
+	a ? b : c;
+	i++; i--;
+	f(...xs); user.name
+	~x
 	`
 
 	tests := []struct {
@@ -137,6 +145,66 @@ func TestNextToken(t *testing.T) {
 		{token.SEMICOLON, ";"},
 		{token.RBRACE, "}"},
 		{token.SEMICOLON, ";"},
+		{token.INT, "2"},
+		{token.POW, "**"},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.PLUS_EQ, "+="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.MINUS_EQ, "-="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.ASTERISK_EQ, "*="},
+		{token.INT, "3"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "x"},
+		{token.SLASH_EQ, "/="},
+		{token.INT, "4"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.AND, "&&"},
+		{token.IDENT, "b"},
+		{token.OR, "||"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.BIT_AND, "&"},
+		{token.BIT_OR, "|"},
+		{token.BIT_XOR, "^"},
+		{token.INT, "1"},
+		{token.SHL, "<<"},
+		{token.INT, "2"},
+		{token.SHR, ">>"},
+		{token.INT, "3"},
+		{token.LT, "<"},
+		{token.GT, ">"},
+		{token.INT, "4"},
+		{token.IDENT, "a"},
+		{token.QUESTION, "?"},
+		{token.IDENT, "b"},
+		{token.COLON, ":"},
+		{token.IDENT, "c"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "i"},
+		{token.INC, "++"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "i"},
+		{token.DEC, "--"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "xs"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "user"},
+		{token.DOT, "."},
+		{token.IDENT, "name"},
+		{token.TILDE, "~"},
+		{token.IDENT, "x"},
 		{token.EOF, ""},
 	}
 	l := New(input)
@@ -155,3 +223,704 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestLineComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{
+			"let x = 5; // set x",
+			[]token.Token{
+				{Type: token.LET, Literal: "let"},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.INT, Literal: "5"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"// first\n// second\nx / y // trailing\nz",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.SLASH, Literal: "/"},
+				{Type: token.IDENT, Literal: "y"},
+				{Type: token.IDENT, Literal: "z"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Fatalf("input %q tokens[%d] wrong. expected=%+v, got=%+v",
+					tt.input, i, expected, tok)
+			}
+		}
+	}
+}
+
+func TestBlockComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{
+			"let /* inline */ x = 5;",
+			[]token.Token{
+				{Type: token.LET, Literal: "let"},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.INT, Literal: "5"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"/*\n * multi\n * line */\nx */* y */ z",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.ASTERISK, Literal: "*"},
+				{Type: token.IDENT, Literal: "z"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"x /* never closed\n",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.ILLEGAL, Literal: "/*"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Fatalf("input %q tokens[%d] wrong. expected=%+v, got=%+v",
+					tt.input, i, expected, tok)
+			}
+		}
+	}
+}
+
+func TestUnterminatedStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedLine    int
+	}{
+		{`"hello`, `"hello`, 1},
+		{"x;\n\"hello\nworld", "\"hello\nworld", 2},
+		{`"escaped quote\"`, `"escaped quote\"`, 1},
+		{`"trailing\`, `"trailing\`, 1},
+	}
+
+	for _, tt := range tests {
+		tokens := Tokens(New(tt.input))
+		// ILLEGALトークンの後はEOFで終わる
+		tok := tokens[len(tokens)-2]
+		if tok.Type != token.ILLEGAL {
+			t.Errorf("input %q - tokentype wrong. expected=%q, got=%q", tt.input, token.ILLEGAL, tok.Type)
+			continue
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %q - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine {
+			t.Errorf("input %q - line wrong. expected=%d, got=%d", tt.input, tt.expectedLine, tok.Line)
+		}
+		if tokens[len(tokens)-1].Type != token.EOF {
+			t.Errorf("input %q - expected EOF after illegal token, got=%+v", tt.input, tokens[len(tokens)-1])
+		}
+	}
+
+	// 閉じられていれば改行を含んでもよい
+	tok := New("\"hello\nworld\"").NextToken()
+	if tok.Type != token.STRING || tok.Literal != "hello\nworld" {
+		t.Errorf("multi-line string wrong. got=%+v", tok)
+	}
+}
+
+func TestRawStrings(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		// 生文字列はエスケープを解釈しない
+		{"`a\\nb`", token.STRING, `a\nb`},
+		{`"a\nb"`, token.STRING, "a\nb"},
+		{"`C:\\path\\to`", token.STRING, `C:\path\to`},
+		{"`say \"hi\"`", token.STRING, `say "hi"`},
+		{"`line1\nline2`", token.STRING, "line1\nline2"},
+		{"``", token.STRING, ""},
+		{"`名前`", token.STRING, "名前"},
+		{"`unterminated", token.ILLEGAL, "`unterminated"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Errorf("input %q - tokentype wrong. expected=%q, got=%q", tt.input, tt.expectedType, tok.Type)
+			continue
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("input %q - literal wrong. expected=%q, got=%q", tt.input, tt.expectedLiteral, tok.Literal)
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Errorf("input %q - expected EOF after string, got=%+v", tt.input, tok)
+		}
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"line1\nline2"`, "line1\nline2"},
+		{`"a\tb"`, "a\tb"},
+		{`"say \"hi\""`, `say "hi"`},
+		{`"back\\slash"`, `back\slash`},
+		{`"\\n"`, `\n`},
+		// 未知のエスケープはそのまま残る
+		{`"\q"`, `\q`},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.STRING {
+			t.Fatalf("input %q - tokentype wrong. expected=%q, got=%q",
+				tt.input, token.STRING, tok.Type)
+		}
+		if tok.Literal != tt.expected {
+			t.Fatalf("input %q - literal wrong. expected=%q, got=%q",
+				tt.input, tt.expected, tok.Literal)
+		}
+		if tok := l.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("input %q - expected EOF after string, got=%+v", tt.input, tok)
+		}
+	}
+}
+
+func TestRadixIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0xFF", "0xFF"},
+		{"0x1a", "0x1a"},
+		{"0o17", "0o17"},
+		{"0b1010", "0b1010"},
+		{"0", "0"},
+		{"10", "10"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input + ";")
+		tok := l.NextToken()
+		if tok.Type != token.INT {
+			t.Fatalf("input %q - tokentype wrong. expected=%q, got=%q",
+				tt.input, token.INT, tok.Type)
+		}
+		if tok.Literal != tt.expected {
+			t.Fatalf("input %q - literal wrong. expected=%q, got=%q",
+				tt.input, tt.expected, tok.Literal)
+		}
+		if tok := l.NextToken(); tok.Type != token.SEMICOLON {
+			t.Fatalf("input %q - expected SEMICOLON, got=%+v", tt.input, tok)
+		}
+	}
+}
+
+func TestDigitSeparators(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"1_000_000", token.INT, "1_000_000"},
+		{"0xFF_FF", token.INT, "0xFF_FF"},
+		// 不正な位置の"_"もINTに含め、パーサーでエラーにする
+		{"1__0", token.INT, "1__0"},
+		{"1_", token.INT, "1_"},
+		// "_"で始まるものは数値ではなく識別子になる
+		{"_5", token.IDENT, "_5"},
+		{"_a", token.IDENT, "_a"},
+		{"_1_000", token.IDENT, "_1_000"},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("input %q - tokentype wrong. expected=%q, got=%q",
+				tt.input, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("input %q - literal wrong. expected=%q, got=%q",
+				tt.input, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// "--"は識別子・")"・"]"の直後でだけデクリメントになる
+func TestDecrement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.TokenType
+	}{
+		{"i--", []token.TokenType{token.IDENT, token.DEC, token.EOF}},
+		{"i --", []token.TokenType{token.IDENT, token.DEC, token.EOF}},
+		{"--5", []token.TokenType{token.MINUS, token.MINUS, token.INT, token.EOF}},
+		{"5--3", []token.TokenType{token.INT, token.MINUS, token.MINUS, token.INT, token.EOF}},
+		{"a[0]--", []token.TokenType{token.IDENT, token.LBRACKET, token.INT, token.RBRACKET,
+			token.DEC, token.EOF}},
+		{"(5)--3", []token.TokenType{token.LPAREN, token.INT, token.RPAREN, token.DEC, token.INT, token.EOF}},
+	}
+
+	for _, tt := range tests {
+		tokens := Tokens(New(tt.input))
+		if len(tokens) != len(tt.expected) {
+			t.Fatalf("input %q - wrong number of tokens. want=%d, got=%d",
+				tt.input, len(tt.expected), len(tokens))
+		}
+		for i, tok := range tokens {
+			if tok.Type != tt.expected[i] {
+				t.Errorf("input %q - tokens[%d] type wrong. want=%q, got=%q",
+					tt.input, i, tt.expected[i], tok.Type)
+			}
+		}
+	}
+}
+
+// 空白とコメントだけの入力はEOFだけになる
+func TestEmptyInput(t *testing.T) {
+	tests := []string{
+		"",
+		"  \t\r\n\n ",
+		"// comment",
+		"// comment\n",
+		"/* block */",
+		"/* multi\nline */ // and line\n",
+	}
+
+	for _, input := range tests {
+		l := New(input)
+		for i := 0; i < 2; i++ {
+			tok := l.NextToken()
+			if tok.Type != token.EOF || tok.Literal != "" {
+				t.Errorf("input %q: token %d is not a clean EOF. got=%+v", input, i, tok)
+			}
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	input := `let x = 5 + 5;`
+
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+		{Type: token.IDENT, Literal: "x", Line: 1, Column: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7},
+		{Type: token.INT, Literal: "5", Line: 1, Column: 9},
+		{Type: token.PLUS, Literal: "+", Line: 1, Column: 11},
+		{Type: token.INT, Literal: "5", Line: 1, Column: 13},
+		{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 14},
+		{Type: token.EOF, Literal: "", Line: 1, Column: 15},
+	}
+
+	tokens := Tokens(New(input))
+	if len(tokens) != len(expected) {
+		t.Fatalf("wrong number of tokens. want=%d, got=%d", len(expected), len(tokens))
+	}
+	for i, tok := range tokens {
+		if tok != expected[i] {
+			t.Errorf("tokens[%d] wrong. want=%+v, got=%+v", i, expected[i], tok)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	input := `let add = fn(x, y) { x + y };
+add(1, "two");`
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENT, "add"},
+		{token.ASSIGN, "="},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.COMMA, ","},
+		{token.IDENT, "y"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.PLUS, "+"},
+		{token.IDENT, "y"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "add"},
+		{token.LPAREN, "("},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.STRING, "two"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	tokens, err := Tokenize(input)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(tokens) != len(expected) {
+		t.Fatalf("wrong number of tokens. want=%d, got=%d", len(expected), len(tokens))
+	}
+	for i, tt := range expected {
+		if tokens[i].Type != tt.expectedType || tokens[i].Literal != tt.expectedLiteral {
+			t.Errorf("tokens[%d] wrong. want=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tokens[i].Type, tokens[i].Literal)
+		}
+	}
+}
+
+func TestTokenizeIllegal(t *testing.T) {
+	tokens, err := Tokenize("let x = 1;\nx @ 2;")
+	if err == nil {
+		t.Fatalf("expected error, got none")
+	}
+	expected := `illegal token "@" at line 2, column 3`
+	if err.Error() != expected {
+		t.Errorf("wrong error. want=%q, got=%q", expected, err)
+	}
+	// エラーがあってもトークンはEOFまで返す
+	if len(tokens) == 0 || tokens[len(tokens)-1].Type != token.EOF {
+		t.Errorf("tokens are not drained to EOF. got=%v", tokens)
+	}
+}
+
+func TestLeadingNewlines(t *testing.T) {
+	input := `let a = 1;
+
+
+let b = 2; // comment
+/* block
+comment */ b
+
+`
+
+	tests := []struct {
+		expectedLiteral  string
+		expectedNewlines int
+	}{
+		{"let", 0},
+		{"a", 0},
+		{"=", 0},
+		{"1", 0},
+		{";", 0},
+		// 空行2つの後のトークン
+		{"let", 3},
+		{"b", 0},
+		{"=", 0},
+		{"2", 0},
+		{";", 0},
+		// コメントの中の改行も数える
+		{"b", 2},
+		{"", 2},
+	}
+
+	tokens := Tokens(New(input))
+	if len(tokens) != len(tests) {
+		t.Fatalf("wrong number of tokens. want=%d, got=%d", len(tests), len(tokens))
+	}
+	for i, tt := range tests {
+		tok := tokens[i]
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tokens[%d] - literal wrong. expected=%q, got=%q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.LeadingNewlines != tt.expectedNewlines {
+			t.Errorf("tokens[%d] (%q) - LeadingNewlines wrong. expected=%d, got=%d",
+				i, tok.Literal, tt.expectedNewlines, tok.LeadingNewlines)
+		}
+	}
+
+	// 入力の先頭の改行も数える
+	if tok := New("\n\nx").NextToken(); tok.LeadingNewlines != 2 {
+		t.Errorf("LeadingNewlines of first token wrong. expected=2, got=%d", tok.LeadingNewlines)
+	}
+}
+
+func TestTabWidth(t *testing.T) {
+	input := "let x = 1;\n\t\tx + 1;"
+
+	tests := []struct {
+		tabWidth       int
+		expectedColumn int
+	}{
+		{0, 3},
+		{1, 3},
+		{4, 9},
+		{8, 17},
+	}
+
+	for _, tt := range tests {
+		l := NewWithOptions(input, Options{TabWidth: tt.tabWidth})
+		tokens := Tokens(l)
+
+		// 2行目の x
+		tok := tokens[5]
+		if tok.Type != token.IDENT || tok.Literal != "x" {
+			t.Fatalf("tokens[5] wrong. got=%+v", tok)
+		}
+		if tok.Line != 2 || tok.Column != tt.expectedColumn {
+			t.Errorf("tab width %d: position of x wrong. want=2:%d, got=%d:%d",
+				tt.tabWidth, tt.expectedColumn, tok.Line, tok.Column)
+		}
+		// タブより後の文字は通常どおり1列ずつ進む
+		plus := tokens[6]
+		if plus.Column != tt.expectedColumn+2 {
+			t.Errorf("tab width %d: column of + wrong. want=%d, got=%d",
+				tt.tabWidth, tt.expectedColumn+2, plus.Column)
+		}
+	}
+}
+
+func TestPosition(t *testing.T) {
+	input := "let x = 5;\n\"café\" + y\n"
+
+	l := New(input)
+	offset, line, column := l.Position()
+	if offset != 0 || line != 1 || column != 1 {
+		t.Fatalf("initial position wrong. got=(%d, %d, %d)", offset, line, column)
+	}
+
+	// 各トークンを読んだ直後の位置(トークンの次の文字)
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedOffset int
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 3, 1, 4},
+		{token.IDENT, 5, 1, 6},
+		{token.ASSIGN, 7, 1, 8},
+		{token.INT, 9, 1, 10},
+		{token.SEMICOLON, 10, 1, 11},
+		// "café" は6バイトだが列は4文字分だけ進む
+		{token.STRING, 18, 2, 7},
+		{token.PLUS, 20, 2, 9},
+		{token.IDENT, 22, 2, 11},
+		// 入力の末尾では最後の改行の次の行の先頭
+		{token.EOF, 23, 3, 1},
+		{token.EOF, 23, 3, 1},
+	}
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		offset, line, column := l.Position()
+		if offset != tt.expectedOffset || line != tt.expectedLine || column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=(%d, %d, %d), got=(%d, %d, %d)",
+				i, tt.expectedOffset, tt.expectedLine, tt.expectedColumn, offset, line, column)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.Token
+	}{
+		{
+			"let 名前 = 1;",
+			[]token.Token{
+				{Type: token.LET, Literal: "let"},
+				{Type: token.IDENT, Literal: "名前"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.INT, Literal: "1"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"café + café",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "café"},
+				{Type: token.PLUS, Literal: "+"},
+				// 結合文字(U+0301)も識別子に含まれる
+				{Type: token.IDENT, Literal: "café"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			// 数字は2文字目以降のみ識別子に含まれる
+			"x1 1x",
+			[]token.Token{
+				{Type: token.IDENT, Literal: "x1"},
+				{Type: token.INT, Literal: "1"},
+				{Type: token.IDENT, Literal: "x"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			// "_"の直後の数字も識別子に含まれる
+			"let _1 = 5;",
+			[]token.Token{
+				{Type: token.LET, Literal: "let"},
+				{Type: token.IDENT, Literal: "_1"},
+				{Type: token.ASSIGN, Literal: "="},
+				{Type: token.INT, Literal: "5"},
+				{Type: token.SEMICOLON, Literal: ";"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+		{
+			"→",
+			[]token.Token{
+				{Type: token.ILLEGAL, Literal: "→"},
+				{Type: token.EOF, Literal: ""},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, expected := range tt.expected {
+			tok := l.NextToken()
+			if tok.Type != expected.Type || tok.Literal != expected.Literal {
+				t.Fatalf("input %q tokens[%d] wrong. expected=%+v, got=%+v",
+					tt.input, i, expected, tok)
+			}
+		}
+	}
+}
+
+func TestCustomKeywords(t *testing.T) {
+	const REPEAT = token.TokenType("REPEAT")
+	input := "repeat (3) { let x = repeated; }"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{REPEAT, "repeat"},
+		{token.LPAREN, "("},
+		{token.INT, "3"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "repeated"},
+		{token.SEMICOLON, ";"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	l := NewWithOptions(input, Options{Keywords: map[string]token.TokenType{"repeat": REPEAT}})
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// 通常の字句解析器には影響しない
+	if tok := New("repeat").NextToken(); tok.Type != token.IDENT {
+		t.Errorf("repeat should be IDENT without the custom keyword. got=%q", tok.Type)
+	}
+}
+
+func TestCustomSymbols(t *testing.T) {
+	const AT = token.TokenType("@")
+	input := "@x + a@b $"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{AT, "@"},
+		{token.IDENT, "x"},
+		{token.PLUS, "+"},
+		{token.IDENT, "a"},
+		{AT, "@"},
+		{token.IDENT, "b"},
+		// 登録していない記号はこれまで通り不正なトークン
+		{token.ILLEGAL, "$"},
+		{token.EOF, ""},
+	}
+
+	l := NewWithOptions(input, Options{Symbols: map[rune]token.TokenType{'@': AT, '+': AT}})
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	// 通常の字句解析器には影響しない
+	if tok := New("@").NextToken(); tok.Type != token.ILLEGAL {
+		t.Errorf("@ should be ILLEGAL without the custom symbol. got=%q", tok.Type)
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  /* c */ y\n/* never"
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENT, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INT, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENT, 2, 11},
+		// 閉じられていないコメントはその先頭の位置になる
+		{token.ILLEGAL, 3, 1},
+		{token.EOF, 3, 9},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position wrong. expected=%d:%d, got=%d:%d",
+				i, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
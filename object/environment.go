@@ -1,5 +1,7 @@
 package object
 
+import "io"
+
 // -----------------------------------------------
 
 // Environment
@@ -10,8 +12,9 @@ func NewEnvironment() *Environment {
 }
 
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	outer  *Environment
+	tracer io.Writer
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -27,6 +30,34 @@ func (e *Environment) Set(name string, val Object) Object {
 	return val
 }
 
+// Assign rebinds name in the nearest environment that already defines it,
+// so closures update the binding they captured. It reports false when name
+// is not bound anywhere in the chain.
+func (e *Environment) Assign(name string, val Object) (Object, bool) {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return val, true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return nil, false
+}
+
+// -----------------------------------------------
+
+// Tracer
+
+// SetTracer sets the writer that binding changes are traced to.
+// Tracing is off while the writer is nil, which is the default.
+func (e *Environment) SetTracer(w io.Writer) {
+	e.tracer = w
+}
+
+func (e *Environment) Tracer() io.Writer {
+	return e.tracer
+}
+
 // -----------------------------------------------
 
 // EnclosedEnvironment
@@ -34,5 +65,6 @@ func (e *Environment) Set(name string, val Object) Object {
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
+	env.tracer = outer.tracer
 	return env
 }
@@ -18,6 +18,8 @@ const (
 	BOOLEAN_OBJ           = "BOOLEAN"
 	NULL_OBJ              = "NULL"
 	RETURN_VALUE_OBJ      = "RETURN_VALUE"
+	BREAK_OBJ             = "BREAK"
+	CONTINUE_OBJ          = "CONTINUE"
 	ERROR_OBJ             = "ERROR"
 	FUNCTION_OBJ          = "FUNCTION"
 	BUILTIN_OBJ           = "BUILTIN"
@@ -65,6 +67,21 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+// IntegerPow computes base**exp by repeated squaring, wrapping on overflow
+// like the other integer operators. exp must not be negative; the evaluator
+// and the VM reject negative exponents before calling it.
+func IntegerPow(base, exp int64) int64 {
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return result
+}
+
 // -----------------------------------------------------
 
 // Boolean
@@ -111,6 +128,33 @@ func (rv *ReturnValue) Inspect() string {
 
 // -----------------------------------------------------
 
+// Break, Continue
+
+// Break and Continue carry a `break` or `continue` out of a loop body up to
+// the loop that handles it, the same way ReturnValue carries a return.
+
+type Break struct {
+}
+
+func (b *Break) Type() ObjectType {
+	return BREAK_OBJ
+}
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+type Continue struct {
+}
+
+func (c *Continue) Type() ObjectType {
+	return CONTINUE_OBJ
+}
+func (c *Continue) Inspect() string {
+	return "continue"
+}
+
+// -----------------------------------------------------
+
 // Error Object
 
 type Error struct {
@@ -242,6 +286,16 @@ type Hashable interface {
 	HashKey() HashKey
 }
 
+// HashKeyOf returns the HashKey of obj so that Go code can key its own maps
+// by Monkey values. Objects that do not implement Hashable are rejected.
+func HashKeyOf(obj Object) (HashKey, error) {
+	hashable, ok := obj.(Hashable)
+	if !ok {
+		return HashKey{}, fmt.Errorf("unusable as hash key: %s", obj.Type())
+	}
+	return hashable.HashKey(), nil
+}
+
 // -----------------------------------------------------
 
 // Hash Object
@@ -273,6 +327,58 @@ func (h *Hash) Inspect() string {
 
 // -----------------------------------------------------
 
+// Deep Equality
+
+// Equal reports whether a and b hold the same value. Integers, strings,
+// booleans and null compare by value, arrays element by element and hashes
+// pair by pair. Any other objects are equal only if they are identical.
+func Equal(a, b Object) bool {
+	if a == b {
+		return true
+	}
+	if a == nil || b == nil || a.Type() != b.Type() {
+		return false
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value == b.(*Integer).Value
+	case *String:
+		return a.Value == b.(*String).Value
+	case *Boolean:
+		return a.Value == b.(*Boolean).Value
+	case *Null:
+		return true
+	case *Array:
+		other := b.(*Array)
+		if len(a.Elements) != len(other.Elements) {
+			return false
+		}
+		for i, el := range a.Elements {
+			if !Equal(el, other.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		other := b.(*Hash)
+		if len(a.Pairs) != len(other.Pairs) {
+			return false
+		}
+		for key, pair := range a.Pairs {
+			otherPair, ok := other.Pairs[key]
+			if !ok || !Equal(pair.Value, otherPair.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// -----------------------------------------------------
+
 // Quote Object
 
 type Quote struct {
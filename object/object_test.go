@@ -26,3 +26,122 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestIntegerPow(t *testing.T) {
+	tests := []struct {
+		base, exp int64
+		expected  int64
+	}{
+		{2, 0, 1},
+		{2, 10, 1024},
+		{-3, 3, -27},
+		{0, 0, 1},
+		{7, 1, 7},
+	}
+
+	for _, tt := range tests {
+		if got := IntegerPow(tt.base, tt.exp); got != tt.expected {
+			t.Errorf("IntegerPow(%d, %d) wrong. want=%d, got=%d", tt.base, tt.exp, tt.expected, got)
+		}
+	}
+}
+
+func TestHashKeyOf(t *testing.T) {
+	tests := []struct {
+		left  Object
+		right Object
+		same  bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Integer{Value: 2}, false},
+		{&String{Value: "one"}, &String{Value: "one"}, true},
+		{&String{Value: "one"}, &String{Value: "two"}, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Boolean{Value: true}, &Boolean{Value: false}, false},
+		{&Integer{Value: 1}, &Boolean{Value: true}, false},
+	}
+
+	for _, tt := range tests {
+		left, err := HashKeyOf(tt.left)
+		if err != nil {
+			t.Fatalf("HashKeyOf(%s) returned error: %s", tt.left.Inspect(), err)
+		}
+		right, err := HashKeyOf(tt.right)
+		if err != nil {
+			t.Fatalf("HashKeyOf(%s) returned error: %s", tt.right.Inspect(), err)
+		}
+		if (left == right) != tt.same {
+			t.Errorf("hash keys of %s and %s: want same=%t, got=%t",
+				tt.left.Inspect(), tt.right.Inspect(), tt.same, left == right)
+		}
+	}
+
+	_, err := HashKeyOf(&Array{Elements: []Object{&Integer{Value: 1}}})
+	if err == nil {
+		t.Fatalf("expected error for unhashable array")
+	}
+	if err.Error() != "unusable as hash key: ARRAY" {
+		t.Errorf("wrong error message. got=%q", err.Error())
+	}
+}
+
+func TestEqual(t *testing.T) {
+	pair := func(key Hashable, value Object) (HashKey, HashPair) {
+		return key.HashKey(), HashPair{Key: key.(Object), Value: value}
+	}
+	hash := func(keys []Hashable, values []Object) *Hash {
+		h := &Hash{Pairs: map[HashKey]HashPair{}}
+		for i, key := range keys {
+			k, p := pair(key, values[i])
+			h.Pairs[k] = p
+		}
+		return h
+	}
+	one := &Integer{Value: 1}
+
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Integer{Value: 2}, false},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&String{Value: "a"}, &String{Value: "b"}, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Boolean{Value: true}, &Boolean{Value: false}, false},
+		{&Null{}, &Null{}, true},
+		{&Integer{Value: 1}, &String{Value: "1"}, false},
+		{
+			&Array{Elements: []Object{one, &String{Value: "a"}}},
+			&Array{Elements: []Object{&Integer{Value: 1}, &String{Value: "a"}}},
+			true,
+		},
+		{
+			&Array{Elements: []Object{one}},
+			&Array{Elements: []Object{one, one}},
+			false,
+		},
+		{
+			&Array{Elements: []Object{&Array{Elements: []Object{one}}}},
+			&Array{Elements: []Object{&Array{Elements: []Object{&Integer{Value: 2}}}}},
+			false,
+		},
+		{
+			hash([]Hashable{&String{Value: "a"}}, []Object{one}),
+			hash([]Hashable{&String{Value: "a"}}, []Object{&Integer{Value: 1}}),
+			true,
+		},
+		{
+			hash([]Hashable{&String{Value: "a"}}, []Object{one}),
+			hash([]Hashable{&String{Value: "b"}}, []Object{one}),
+			false,
+		},
+		{&Builtin{}, &Builtin{}, false},
+	}
+
+	for i, tt := range tests {
+		if Equal(tt.a, tt.b) != tt.expected {
+			t.Errorf("tests[%d]: Equal(%s, %s) want=%t", i, tt.a.Inspect(), tt.b.Inspect(), tt.expected)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package parser
 import (
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/tamurayoshiya/monkey/ast"
 	"github.com/tamurayoshiya/monkey/lexer"
@@ -16,53 +17,108 @@ import (
 type (
 	prefixParseFn func() ast.Expression               // 前置構文解析関数 (prefix parsing function)
 	infixParseFn  func(ast.Expression) ast.Expression // 中置構文解析関数 (infix parsing function)
+
+	// 埋め込み側がRegisterPrefix/RegisterInfixで登録する構文解析関数の型
+	PrefixParseFn = func() ast.Expression
+	InfixParseFn  = func(ast.Expression) ast.Expression
 )
 
 // -------------------------------------------------------
 
 type Parser struct {
 	l      *lexer.Lexer // 字句解析器インスタンスへのポインタ
-	errors []string
+	errors []ParseError
 
 	curToken  token.Token // 現在のトークン(cur -> current)
 	peekToken token.Token // 次のトークン(peek 覗く)
 
 	prefixParseFns map[token.TokenType]prefixParseFn
 	infixParseFns  map[token.TokenType]infixParseFn
+	precedences    map[token.TokenType]int // SetPrecedenceで上書きした優先順位
+
+	options Options
+
+	depth       int  // 現在の式とブロックの入れ子の深さ
+	tooDeep     bool // 入れ子の深さの上限を超えたか
+	depthErrors int  // 上限を超えた時点のエラー数。それ以降のエラーは連鎖したものなので捨てる
+}
+
+// 入れ子の深さの上限の既定値
+const DefaultMaxDepth = 1000
+
+// パーサーの動作を変えるオプション。ゼロ値はNewと同じ動作になる
+type Options struct {
+	// 文の区切りの;を必須にする
+	// ブロックで終わる文(if式やwhile文など)と、ブロック内の最後の文は;を省略できる
+	RequireSemicolons bool
+	// RequireSemicolonsのとき、入力の最後の式文だけは;を省略できるようにする
+	// それ以外の文の区切りの規則は変えない
+	// REPLで `1 + 1` のような式をそのまま評価するためのもの
+	AllowTrailingExpr bool
+	// 式とブロックの入れ子の深さの上限。0以下ならDefaultMaxDepth
+	// 深い入れ子でパーサーの再帰がスタックを使い果たすのを防ぐ
+	MaxDepth int
+	// 引数リスト、配列リテラル、ハッシュリテラルの末尾カンマを許可する
+	// falseなら `add(1, 2,)` のような末尾カンマはエラーになる
+	AllowTrailingComma bool
+	// 改行を;と同じく文の区切りとして扱う
+	// 式は改行をまたいで続かないので、a の次の行の -b は (a - b) ではなく別の文 (-b) になる
+	// 演算子や,の後の改行はこれまでどおり式の途中として扱われる
+	StatementsEndOnNewline bool
 }
 
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []ParseError{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.TILDE, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
+	p.registerPrefix(token.NULL, p.parseNull)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
-	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.LBRACE, p.parseBraceExpression)
 	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.ELLIPSIS, p.parseMisplacedSpread)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
 	p.registerInfix(token.MINUS, p.parseInfixExpression)
 	p.registerInfix(token.SLASH, p.parseInfixExpression)
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
+	p.registerInfix(token.POW, p.parseInfixExpression)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.QUESTION, p.parseTernaryExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHL, p.parseInfixExpression)
+	p.registerInfix(token.SHR, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseMemberExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.INC, p.parsePostfixExpression)
+	p.registerInfix(token.DEC, p.parsePostfixExpression)
+	for compound := range compoundAssignOperators {
+		p.registerInfix(compound, p.parseCompoundAssignExpression)
+	}
 
 	// 2つのトークンを読み込む。curTokenとpeekTokenの両方がセットされる
 	p.nextToken()
@@ -71,6 +127,13 @@ func New(l *lexer.Lexer) *Parser {
 	return p
 }
 
+// オプションを指定してパーサーを生成する
+func NewWithOptions(l *lexer.Lexer, options Options) *Parser {
+	p := New(l)
+	p.options = options
+	return p
+}
+
 // -------------------------------------------------------
 
 // トークンを進める処理
@@ -89,41 +152,264 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
+	p.ParseStream(func(stmt ast.Statement) bool {
+		program.Statements = append(program.Statements, stmt)
+		return true
+	})
+
+	// ルートノードを返却
+	return program
+}
+
+// 文を1つずつパースしてfnに渡す。fnがfalseを返したらそこで止まる
+// 文の列を保持しないので、大きな入力を少しずつ処理できる
+// 止まったときは次の文の先頭まで進んでいるので、もう一度呼べば続きからパースする
+// (カンマ区切りのlet文の途中で止めた場合は、残りの束縛は渡されない)
+func (p *Parser) ParseStream(fn func(ast.Statement) bool) {
+	defer func() {
+		if p.tooDeep {
+			p.errors = p.errors[:p.depthErrors]
+		}
+	}()
+
 	// トークンをウォーク
 	for p.curToken.Type != token.EOF {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			program.Statements = append(program.Statements, stmt)
+		// 空の文(単独の;)は読み飛ばす
+		if p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		stmts := p.parseStatements()
+		if stmts == nil {
+			// 壊れた文の残りを読み飛ばし、エラーが連鎖しないようにする
+			p.synchronize()
+		} else {
+			p.checkStatementEnd(stmts[len(stmts)-1])
 		}
 		p.nextToken()
+
+		for _, stmt := range stmts {
+			if !fn(stmt) {
+				return
+			}
+		}
 	}
+}
 
-	// ルートノードを返却
-	return program
+// 文を含まない、ただ1つの式としてパースする
+// 設定値などにMonkeyの式を埋め込む用途のためのもので、let文やreturn文は受け付けない
+// 末尾の;は省略できるが、式の後に他のトークンが続く場合はエラーになる
+// エラーの場合は最初のエラー(ParseError)を返す
+func (p *Parser) ParseExpressionOnly() (ast.Expression, error) {
+	switch p.curToken.Type {
+	case token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE:
+		msg := fmt.Sprintf("expected an expression, got statement %q", p.curToken.Literal)
+		p.addError(p.curToken, msg)
+		return nil, p.errors[0]
+	}
+
+	exp := p.parseExpression(LOWEST)
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	if len(p.errors) == 0 && !p.peekTokenIs(token.EOF) {
+		msg := fmt.Sprintf("unexpected %s after expression", p.peekToken.Type)
+		p.addError(p.peekToken, msg)
+	}
+
+	if p.tooDeep {
+		p.errors = p.errors[:p.depthErrors]
+	}
+	if len(p.errors) > 0 {
+		return nil, p.errors[0]
+	}
+	return exp, nil
 }
 
 // -------------------------------------------------------
 
 // 文、式文のパース
 
+// RequireSemicolonsのとき、パースし終えた文の後に区切りがあるかを確認する
+// curTokenは文の最後のトークンを指している
+func (p *Parser) checkStatementEnd(last ast.Statement) {
+	if !p.options.RequireSemicolons {
+		return
+	}
+	if p.curTokenIs(token.SEMICOLON) || p.curTokenIs(token.RBRACE) || p.peekTokenIs(token.RBRACE) {
+		return
+	}
+	// 改行で区切る場合は、入力の最後の行も改行なしで終われる
+	if p.peekAfterNewline() || (p.options.StatementsEndOnNewline && p.peekTokenIs(token.EOF)) {
+		return
+	}
+	if p.peekTokenIs(token.EOF) && p.options.AllowTrailingExpr {
+		if _, ok := last.(*ast.ExpressionStatement); ok {
+			return
+		}
+	}
+	p.peekError(token.SEMICOLON)
+}
+
+// 文の並びの中の1文をパースする
+// let a = 1, b = 2; は let a = 1; let b = 2; と同じく2つのlet文になるため、スライスで返す
+func (p *Parser) parseStatements() []ast.Statement {
+	if p.curTokenIs(token.LET) {
+		return p.parseLetStatements()
+	}
+	stmt := p.parseStatement()
+	if stmt == nil {
+		return nil
+	}
+	return []ast.Statement{stmt}
+}
+
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
 	case token.LET:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		stmt := p.parseWhileStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.FOR:
+		stmt := p.parseForStatement()
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	case token.BREAK:
+		return p.parseBreakStatement()
+	case token.CONTINUE:
+		return p.parseContinueStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
-func (p *Parser) parseLetStatement() *ast.LetStatement {
-	stmt := &ast.LetStatement{Token: p.curToken}
+// エラー後の同期
+// 次の文の先頭まで読み進める。curTokenが';'またはEOFになるか、
+// peekTokenが文の先頭となるキーワードになった時点で止まる
+func (p *Parser) synchronize() {
+	for !p.curTokenIs(token.SEMICOLON) && !p.curTokenIs(token.EOF) {
+		switch p.peekToken.Type {
+		case token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE:
+			return
+		}
+		p.nextToken()
+	}
+}
+
+// カンマ区切りで複数の束縛を宣言するlet文のパース
+// 初期化式は左から順に独立してパースされる
+// let a, b = 1, 2; の形は1つのMultiLetStatementになり、後ろに束縛を続けられない
+func (p *Parser) parseLetStatements() []ast.Statement {
+	stmt := p.parseLetStatement()
+	if stmt == nil {
+		return nil
+	}
+	first, ok := stmt.(*ast.LetStatement)
+	if !ok {
+		return []ast.Statement{stmt}
+	}
+	statements := []ast.Statement{stmt}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		// 2つ目以降の束縛も先頭の'let'トークンを共有する
+		next := p.parseLetBinding(first.Token)
+		if next == nil {
+			return nil
+		}
+		statements = append(statements, next)
+	}
+	return statements
+}
 
+// let文のパース
+// 名前の後に','が続けば let a, b = 1, 2; の形としてMultiLetStatementを返す
+func (p *Parser) parseLetStatement() ast.Statement {
+	letToken := p.curToken
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 
+	// nilの*ast.LetStatementなどをそのまま返すとnilでないインターフェースになる
+	if p.peekTokenIs(token.COMMA) {
+		stmt := p.parseMultiLetStatement(letToken)
+		if stmt == nil {
+			return nil
+		}
+		return stmt
+	}
+	stmt := p.parseLetBinding(letToken)
+	if stmt == nil {
+		return nil
+	}
+	return stmt
+}
+
+// 複数の名前を束縛するlet文のパース。curTokenは最初の名前
+// 構造: let <identifier>, <identifier>, ... = <expression>, <expression>, ...;
+func (p *Parser) parseMultiLetStatement(letToken token.Token) *ast.MultiLetStatement {
+	stmt := &ast.MultiLetStatement{Token: letToken}
+
+	stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Names = append(stmt.Names, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+	}
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Values = append(stmt.Values, p.parseExpression(LOWEST))
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Values = append(stmt.Values, p.parseExpression(LOWEST))
+	}
+
+	if len(stmt.Names) != len(stmt.Values) {
+		unit := "values"
+		if len(stmt.Values) == 1 {
+			unit = "value"
+		}
+		msg := fmt.Sprintf("%d names but %d %s", len(stmt.Names), len(stmt.Values), unit)
+		p.addError(letToken, msg)
+		return nil
+	}
+
+	// 関数に束縛先の名前を持たせる
+	for i, value := range stmt.Values {
+		if fl, ok := value.(*ast.FunctionLiteral); ok {
+			fl.Name = stmt.Names[i].Value
+		}
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Semicolon = p.curToken
+	}
+
+	return stmt
+}
+
+// let文の束縛 <identifier> = <expression> 1つ分のパース。curTokenは名前
+func (p *Parser) parseLetBinding(letToken token.Token) *ast.LetStatement {
+	stmt := &ast.LetStatement{Token: letToken}
+
 	stmt.Name = &ast.Identifier{
 		Token: p.curToken,
 		Value: p.curToken.Literal,
@@ -136,8 +422,14 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
+	// 関数に束縛先の名前を持たせる
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+		fl.Name = stmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
+		stmt.Semicolon = p.curToken
 	}
 
 	return stmt
@@ -152,6 +444,115 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 	for p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
+		stmt.Semicolon = p.curToken
+	}
+	return stmt
+}
+
+// while文のパース
+// 条件式はif式と同じく括弧で囲む
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// for文のパース
+// for (<init>; <condition>; <post>) { ... } の各節は省略できる
+func (p *Parser) parseForStatement() *ast.ForStatement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	// 初期化節: 文のパースで末尾の';'まで読まれる
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	} else {
+		p.nextToken()
+		stmt.Init = p.parseStatement()
+		if stmt.Init == nil {
+			return nil
+		}
+		if !p.curTokenIs(token.SEMICOLON) && !p.expectPeek(token.SEMICOLON) {
+			return nil
+		}
+	}
+
+	// 条件節
+	if !p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.SEMICOLON) {
+		return nil
+	}
+
+	// 更新節
+	if !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+		stmt.Post = p.parseStatement()
+		if stmt.Post == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// break文のパース
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Semicolon = p.curToken
+	}
+	return stmt
+}
+
+// continue文のパース
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+		stmt.Semicolon = p.curToken
 	}
 	return stmt
 }
@@ -160,10 +561,24 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
-	stmt.Expression = p.parseExpression(LOWEST)
+	switch {
+	case p.curTokenIs(token.IF):
+		// ブロックで終わる式で始まる式文は、セミコロンがなくてもブロックの直後で終わる
+		// if (x) { 1 } -2 は if式 と -2 の2つの文になる
+		stmt.Expression = p.parseIfExpression()
+	case p.curTokenIs(token.FUNCTION):
+		// 関数リテラルも同様。ただし同じ行で続く ( は即時呼び出しとして式の続きになる
+		stmt.Expression = p.parseFunctionLiteral()
+		if p.peekTokenIs(token.LPAREN) && !p.peekAfterNewline() {
+			stmt.Expression = p.parseInfixExpressions(stmt.Expression, LOWEST)
+		}
+	default:
+		stmt.Expression = p.parseExpression(LOWEST)
+	}
 
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
+		stmt.Semicolon = p.curToken
 	}
 
 	return stmt
@@ -174,16 +589,61 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	defer p.leaveNesting()
+	if !p.enterNesting() {
+		return block
+	}
+
 	p.nextToken()
+	p.parseBlockRest(block)
+	return block
+}
 
+// ブロックの残りの文を } まで読んでblockに追加する。curTokenは次の文の先頭
+func (p *Parser) parseBlockRest(block *ast.BlockStatement) {
 	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
-		stmt := p.parseStatement()
-		if stmt != nil {
-			block.Statements = append(block.Statements, stmt)
+		if p.curTokenIs(token.SEMICOLON) {
+			p.nextToken()
+			continue
+		}
+		stmts := p.parseStatements()
+		if stmts != nil {
+			p.checkStatementEnd(stmts[len(stmts)-1])
 		}
+		block.Statements = append(block.Statements, stmts...)
 		p.nextToken()
 	}
-	return block
+	if p.curTokenIs(token.RBRACE) {
+		block.Rbrace = p.curToken
+	}
+}
+
+// 入れ子を1段深くする
+// 上限を超えた場合はエラーを1つだけ記録し、外側のパースがすぐ終わるように入力の末尾まで進めてfalseを返す
+func (p *Parser) enterNesting() bool {
+	p.depth++
+	maxDepth := p.options.MaxDepth
+	if maxDepth <= 0 {
+		maxDepth = DefaultMaxDepth
+	}
+	if p.depth <= maxDepth {
+		return true
+	}
+
+	if !p.tooDeep {
+		p.tooDeep = true
+		msg := fmt.Sprintf("maximum nesting depth %d exceeded", maxDepth)
+		p.addError(p.curToken, msg)
+		p.depthErrors = len(p.errors)
+	}
+	for !p.curTokenIs(token.EOF) {
+		p.nextToken()
+	}
+	return false
+}
+
+func (p *Parser) leaveNesting() {
+	p.depth--
 }
 
 // -------------------------------------------------------
@@ -191,14 +651,22 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 // 式のパース
 
 func (p *Parser) parseExpression(precedence int) ast.Expression {
+	defer p.leaveNesting()
+	if !p.enterNesting() {
+		return nil
+	}
+
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
 		return nil
 	}
-	leftExp := prefix()
+	return p.parseInfixExpressions(prefix(), precedence)
+}
 
-	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
+// leftExpを左辺として、precedenceより強い中置演算子が続く限り読み進める
+func (p *Parser) parseInfixExpressions(leftExp ast.Expression, precedence int) ast.Expression {
+	for !p.peekTokenIs(token.SEMICOLON) && !p.peekAfterNewline() && precedence < p.peekPrecedence() {
 		infix := p.infixParseFns[p.peekToken.Type]
 		if infix == nil {
 			return leftExp
@@ -214,7 +682,19 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
 	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
+	p.addError(p.curToken, msg)
+}
+
+// 不正なトークンのエラー
+// 字句解析器は閉じられていない文字列を"または`から始まるILLEGALトークンにする
+func (p *Parser) parseIllegal() ast.Expression {
+	if strings.HasPrefix(p.curToken.Literal, "\"") || strings.HasPrefix(p.curToken.Literal, "`") {
+		msg := fmt.Sprintf("unterminated string literal at line %d", p.curToken.Line)
+		p.addError(p.curToken, msg)
+		return nil
+	}
+	p.noPrefixParseFnError(p.curToken.Type)
+	return nil
 }
 
 // 識別子のパース
@@ -238,10 +718,16 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{
 		Token: p.curToken,
 	}
-	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if !validDigitSeparators(p.curToken.Literal) {
+		msg := fmt.Sprintf("invalid digit separator in %q", p.curToken.Literal)
+		p.addError(p.curToken, msg)
+		return nil
+	}
+	literal := strings.ReplaceAll(p.curToken.Literal, "_", "")
+	value, err := strconv.ParseInt(literal, 0, 64)
 	if err != nil {
 		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(p.curToken, msg)
 		return nil
 	}
 
@@ -249,6 +735,19 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+// 数字区切りの"_"は数字と数字の間にのみ置ける(先頭・末尾・連続は不可)
+// 0x などの接頭辞の直後も不可
+func validDigitSeparators(literal string) bool {
+	digits := literal
+	if len(digits) > 2 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		digits = digits[2:]
+	}
+	if strings.HasPrefix(digits, "_") || strings.HasSuffix(digits, "_") {
+		return false
+	}
+	return !strings.Contains(digits, "__")
+}
+
 // 真偽値リテラルのパース
 func (p *Parser) parseBoolean() ast.Expression {
 	lit := &ast.Boolean{
@@ -258,6 +757,11 @@ func (p *Parser) parseBoolean() ast.Expression {
 	return lit
 }
 
+// nullリテラルのパース
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.NullLiteral{Token: p.curToken}
+}
+
 // 前置演算子のパース
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
@@ -281,9 +785,140 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	// べき乗は右結合: 2 ** 3 ** 2 は 2 ** (3 ** 2)
+	// 右辺を1つ低い優先順位で解析することで、同じ演算子を右側に取り込む
+	if expression.Token.Type == token.POW {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
+	if precedence == LESSGREATER || precedence == EQUALS {
+		return p.parseComparisonChain(expression)
+	}
+	return expression
+}
+
+// 比較演算子の連鎖のパース
+// a < b < c は ((a < b) < c) ではなく、(a < b) && (b < c) の意味の ComparisonChain にする
+// 連鎖するのは同じ優先順位の比較演算子同士のみ(a < b == c は従来どおり ((a < b) == c))
+// 連鎖しなければ first をそのまま返す
+func (p *Parser) parseComparisonChain(first *ast.InfixExpression) ast.Expression {
+	precedence := p.precedenceOf(first.Token.Type)
+	if p.peekAfterNewline() || p.peekPrecedence() != precedence {
+		return first
+	}
+
+	chain := &ast.ComparisonChain{
+		Token:     first.Token,
+		Operands:  []ast.Expression{first.Left, first.Right},
+		Operators: []string{first.Operator},
+	}
+	for !p.peekAfterNewline() && p.peekPrecedence() == precedence {
+		p.nextToken()
+		chain.Operators = append(chain.Operators, p.curToken.Literal)
+		p.nextToken()
+		chain.Operands = append(chain.Operands, p.parseExpression(precedence))
+	}
+	return chain
+}
+
+// 代入式のパース
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	name, ok := left.(*ast.Identifier)
+	if !ok {
+		p.invalidAssignmentTargetError(left)
+		return nil
+	}
+	expression := &ast.AssignExpression{
+		Token: p.curToken,
+		Name:  name,
+	}
+
+	p.nextToken()
+	// 代入は右結合: x = y = 3 は x = (y = 3)
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+// 後置式のパース
+// 左辺は識別子のみ許可する
+func (p *Parser) parsePostfixExpression(left ast.Expression) ast.Expression {
+	if _, ok := left.(*ast.Identifier); !ok {
+		p.invalidAssignmentTargetError(left)
+		return nil
+	}
+	return &ast.PostfixExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+}
+
+// 複合代入演算子と、それが表す二項演算子の対応
+var compoundAssignOperators = map[token.TokenType]token.TokenType{
+	token.PLUS_EQ:     token.PLUS,
+	token.MINUS_EQ:    token.MINUS,
+	token.ASTERISK_EQ: token.ASTERISK,
+	token.SLASH_EQ:    token.SLASH,
+}
+
+// 複合代入式のパース
+// x += 1 は x = x + 1 と同じ代入式に展開する
+func (p *Parser) parseCompoundAssignExpression(left ast.Expression) ast.Expression {
+	name, ok := left.(*ast.Identifier)
+	if !ok {
+		p.invalidAssignmentTargetError(left)
+		return nil
+	}
+
+	// 演算子のトークンタイプはリテラルと同じ文字列になっている
+	operator := compoundAssignOperators[p.curToken.Type]
+	infix := &ast.InfixExpression{
+		Token:    token.Token{Type: operator, Literal: string(operator)},
+		Operator: string(operator),
+		Left:     &ast.Identifier{Token: name.Token, Value: name.Value},
+	}
+	expression := &ast.AssignExpression{
+		Token: token.Token{Type: token.ASSIGN, Literal: "="},
+		Name:  name,
+		Value: infix,
+	}
+
+	p.nextToken()
+	infix.Right = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+func (p *Parser) invalidAssignmentTargetError(left ast.Expression) {
+	target := "<nil>"
+	if left != nil {
+		target = left.String()
+	}
+	msg := fmt.Sprintf("invalid assignment target %s", target)
+	p.addError(p.curToken, msg)
+}
+
+// 三項演算子式のパース
+func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression {
+	expression := &ast.TernaryExpression{
+		Token:     p.curToken,
+		Condition: condition,
+	}
+
+	p.nextToken()
+	expression.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	// 右結合: a ? b : c ? d : e は a ? b : (c ? d : e)
+	expression.Alternative = p.parseExpression(TERNARY - 1)
+
 	return expression
 }
 
@@ -366,8 +1001,18 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	}
 	identifiers = append(identifiers, ident)
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+	for {
+		if !p.expectPeekOneOf(token.COMMA, token.RPAREN) {
+			return nil
+		}
+		if p.curTokenIs(token.RPAREN) {
+			return identifiers
+		}
+		if p.peekTokenIs(token.RPAREN) {
+			p.checkTrailingComma(token.RPAREN)
+			p.nextToken()
+			return identifiers
+		}
 		p.nextToken()
 		ident := &ast.Identifier{
 			Token: p.curToken,
@@ -375,12 +1020,6 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		}
 		identifiers = append(identifiers, ident)
 	}
-
-	if !p.expectPeek(token.RPAREN) {
-		return nil
-	}
-
-	return identifiers
 }
 
 // 関数コールのパース
@@ -389,21 +1028,52 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 		Token:    p.curToken,
 		Function: function,
 	}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = p.parseList(token.RPAREN, p.parseCallArgument)
+	if exp.Arguments != nil {
+		exp.Rparen = p.curToken
+	}
 	return exp
 }
 
+// 関数呼び出しの引数のパース
+// 先頭に ... がある引数は、配列を展開して渡す引数になる
+func (p *Parser) parseCallArgument() ast.Expression {
+	if !p.curTokenIs(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+	spread := &ast.SpreadExpression{Token: p.curToken}
+	p.nextToken()
+	spread.Value = p.parseExpression(LOWEST)
+	return spread
+}
+
+// 関数呼び出しの引数以外に現れた ... はエラーにする
+func (p *Parser) parseMisplacedSpread() ast.Expression {
+	p.addError(p.curToken, "spread operator ... is only allowed in call arguments")
+	return nil
+}
+
 // 配列リテラルのパース
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{
 		Token: p.curToken,
 	}
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements != nil {
+		array.Rbracket = p.curToken
+	}
 	return array
 }
 
 // カンマ区切りの構文解析が必要な箇所（引数, 配列）のパース
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	return p.parseList(end, func() ast.Expression {
+		return p.parseExpression(LOWEST)
+	})
+}
+
+// endで終わるカンマ区切りのリストのパース。各要素はparseElementでパースする
+func (p *Parser) parseList(end token.TokenType, parseElement func() ast.Expression) []ast.Expression {
 	list := []ast.Expression{}
 
 	if p.peekTokenIs(end) {
@@ -412,16 +1082,22 @@ func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	}
 
 	p.nextToken()
-	list = append(list, p.parseExpression(LOWEST))
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+	list = append(list, parseElement())
+	for {
+		if !p.expectPeekOneOf(token.COMMA, end) {
+			return nil
+		}
+		if p.curTokenIs(end) {
+			return list
+		}
+		if p.peekTokenIs(end) {
+			p.checkTrailingComma(end)
+			p.nextToken()
+			return list
+		}
 		p.nextToken()
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, parseElement())
 	}
-	if !p.expectPeek(end) {
-		return nil
-	}
-	return list
 }
 
 // 配列リテラルのパース
@@ -436,20 +1112,107 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	exp.Rbracket = p.curToken
 	return exp
 }
 
-// ハッシュ・リテラルのパース
-func (p *Parser) parseHashLiteral() ast.Expression {
-	hash := &ast.HashLiteral{
+// メンバーアクセスのパース
+// h.key は h["key"] の糖衣構文として、添字が文字列リテラルのIndexExpressionになる
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{
+		Token: p.curToken,
+		Left:  left,
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	tok := p.curToken
+	tok.Type = token.STRING
+	exp.Index = &ast.StringLiteral{Token: tok, Value: tok.Literal}
+	return exp
+}
+
+// { で始まる式のパース
+// {} は空のハッシュ、{ の直後が文のキーワードか ; ならブロック式
+// それ以外は最初の式を読み、続くトークンが : ならハッシュ、そうでなければブロック式とする
+func (p *Parser) parseBraceExpression() ast.Expression {
+	switch p.peekToken.Type {
+	case token.RBRACE:
+		return p.parseHashLiteral()
+	case token.LET, token.RETURN, token.WHILE, token.FOR, token.BREAK, token.CONTINUE, token.SEMICOLON:
+		return p.parseBlockExpression()
+	}
+
+	lbrace := p.curToken
+	p.nextToken()
+	first := p.curToken
+	exp := p.parseExpression(LOWEST)
+	if exp == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.COLON) {
+		return p.parseHashPairs(lbrace, exp)
+	}
+	return p.parseBlockExpressionFrom(lbrace, &ast.ExpressionStatement{Token: first, Expression: exp})
+}
+
+// ブロック式のパース
+func (p *Parser) parseBlockExpression() ast.Expression {
+	return &ast.BlockExpression{
 		Token: p.curToken,
+		Block: p.parseBlockStatement(),
 	}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+}
 
-	for !p.peekTokenIs(token.RBRACE) {
+// 最初の式文を読み終えたブロック式の残りのパース
+func (p *Parser) parseBlockExpressionFrom(lbrace token.Token, first *ast.ExpressionStatement) ast.Expression {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
-		key := p.parseExpression(LOWEST)
+		first.Semicolon = p.curToken
+	}
+	p.checkStatementEnd(first)
+
+	block := &ast.BlockStatement{
+		Token:      lbrace,
+		Statements: []ast.Statement{first},
+	}
+	exp := &ast.BlockExpression{Token: lbrace, Block: block}
 
+	defer p.leaveNesting()
+	if !p.enterNesting() {
+		return exp
+	}
+
+	p.nextToken()
+	p.parseBlockRest(block)
+	return exp
+}
+
+// ハッシュ・リテラルのパース
+func (p *Parser) parseHashLiteral() ast.Expression {
+	lbrace := p.curToken
+	if p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		return &ast.HashLiteral{
+			Token:  lbrace,
+			Pairs:  []ast.HashPair{},
+			Rbrace: p.curToken,
+		}
+	}
+
+	p.nextToken()
+	key := p.parseExpression(LOWEST)
+	return p.parseHashPairs(lbrace, key)
+}
+
+// 最初のキーを読み終えたハッシュ・リテラルの残りのパース
+func (p *Parser) parseHashPairs(lbrace token.Token, key ast.Expression) ast.Expression {
+	hash := &ast.HashLiteral{
+		Token: lbrace,
+	}
+	hash.Pairs = []ast.HashPair{}
+
+	for {
 		if !p.expectPeek(token.COLON) {
 			return nil
 		}
@@ -457,15 +1220,26 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, ast.HashPair{Key: key, Value: value})
 
-		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+		if p.peekTokenIs(token.RBRACE) {
+			break
+		}
+		if !p.expectPeek(token.COMMA) {
 			return nil
 		}
+		if p.peekTokenIs(token.RBRACE) {
+			p.checkTrailingComma(token.RBRACE)
+			break
+		}
+
+		p.nextToken()
+		key = p.parseExpression(LOWEST)
 	}
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.Rbrace = p.curToken
 	return hash
 }
 
@@ -506,6 +1280,51 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 
 // -------------------------------------------------------
 
+// 埋め込み側から独自の演算子を追加するためのAPI
+// 独自の記号は lexer.Options の Symbols でトークンにしておく
+
+// トークンタイプに前置構文解析関数を登録する。組み込みの登録は上書きされる
+func (p *Parser) RegisterPrefix(tokenType token.TokenType, fn PrefixParseFn) {
+	p.registerPrefix(tokenType, fn)
+}
+
+// トークンタイプに中置構文解析関数を登録する。組み込みの登録は上書きされる
+// 中置演算子として働かせるには、SetPrecedenceでLOWESTより高い優先順位を設定する
+func (p *Parser) RegisterInfix(tokenType token.TokenType, fn InfixParseFn) {
+	p.registerInfix(tokenType, fn)
+}
+
+// トークンタイプの優先順位を設定する。このパーサーにだけ効き、組み込みの優先順位より優先される
+func (p *Parser) SetPrecedence(tokenType token.TokenType, precedence int) {
+	if p.precedences == nil {
+		p.precedences = make(map[token.TokenType]int)
+	}
+	p.precedences[tokenType] = precedence
+}
+
+// 構文解析関数の中から使う、現在のトークン
+func (p *Parser) CurToken() token.Token {
+	return p.curToken
+}
+
+// 構文解析関数の中から使う、次のトークン
+func (p *Parser) PeekToken() token.Token {
+	return p.peekToken
+}
+
+// トークンを1つ進める
+func (p *Parser) NextToken() {
+	p.nextToken()
+}
+
+// 現在のトークンから、precedenceより強く結合する部分を式として解析する
+// 前置演算子の右辺ならPREFIX、左結合の中置演算子の右辺ならその演算子の優先順位を渡す
+func (p *Parser) ParseExpression(precedence int) ast.Expression {
+	return p.parseExpression(precedence)
+}
+
+// -------------------------------------------------------
+
 // 優先順位ヘルパー
 
 const (
@@ -513,39 +1332,74 @@ const (
 	// _ = 0, LOWEST = 1, EQUALS = 2... と割り当てられる
 	_ int = iota
 	LOWEST
+	ASSIGN      // =
+	TERNARY     // X ? Y : Z
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
 	LESSGREATER // > または <
+	BITWISE     // & | ^ (比較より強く結合する: a & 1 == 0 は (a & 1) == 0)
+	SHIFT       // << または >>
 	SUM         // +
 	PRODUCT     // *
 	PREFIX      // -X または !X
+	POWER       // X ** Y (前置演算子より強く結合する: -2 ** 2 は -(2 ** 2))
 	CALL        // myFunction(X)
 	INDEX       // array[index]
+	POSTFIX     // X++ または X--
 )
 
 // 優先順位テーブル（トークンタイプとその優先順の関連付け）
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:      ASSIGN,
+	token.PLUS_EQ:     ASSIGN,
+	token.MINUS_EQ:    ASSIGN,
+	token.ASTERISK_EQ: ASSIGN,
+	token.SLASH_EQ:    ASSIGN,
+	token.QUESTION:    TERNARY,
+	token.OR:          LOGICAL_OR,
+	token.AND:         LOGICAL_AND,
+	token.EQ:          EQUALS,
+	token.NOT_EQ:      EQUALS,
+	token.LT:          LESSGREATER,
+	token.GT:          LESSGREATER,
+	token.BIT_AND:     BITWISE,
+	token.BIT_OR:      BITWISE,
+	token.BIT_XOR:     BITWISE,
+	token.SHL:         SHIFT,
+	token.SHR:         SHIFT,
+	token.PLUS:        SUM,
+	token.MINUS:       SUM,
+	token.SLASH:       PRODUCT,
+	token.ASTERISK:    PRODUCT,
+	token.POW:         POWER,
+	token.LPAREN:      CALL,
+	token.LBRACKET:    INDEX,
+	token.DOT:         INDEX,
+	token.INC:         POSTFIX,
+	token.DEC:         POSTFIX,
+}
+
+// StatementsEndOnNewlineのとき、次のトークンが改行の後にあるかどうか
+// その場合、式はそこで終わる
+func (p *Parser) peekAfterNewline() bool {
+	return p.options.StatementsEndOnNewline && p.peekToken.LeadingNewlines > 0
 }
 
 func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
-		return p
-	}
-	return LOWEST
+	return p.precedenceOf(p.peekToken.Type)
 }
 
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
+	return p.precedenceOf(p.curToken.Type)
+}
+
+func (p *Parser) precedenceOf(t token.TokenType) int {
+	if precedence, ok := p.precedences[t]; ok {
+		return precedence
+	}
+	if precedence, ok := precedences[t]; ok {
+		return precedence
 	}
 	return LOWEST
 }
@@ -564,16 +1418,119 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
+// 記号のトークンタイプの名前。エラーメッセージで使う
+var tokenNames = map[token.TokenType]string{
+	token.COMMA:     "COMMA",
+	token.SEMICOLON: "SEMICOLON",
+	token.COLON:     "COLON",
+	token.LPAREN:    "LPAREN",
+	token.RPAREN:    "RPAREN",
+	token.LBRACKET:  "LBRACKET",
+	token.RBRACKET:  "RBRACKET",
+	token.LBRACE:    "LBRACE",
+	token.RBRACE:    "RBRACE",
+}
+
+// エラーメッセージに書くトークンタイプの名前
+// 名前のない記号はトークンタイプ(記号そのもの)を返す
+func tokenName(t token.TokenType) string {
+	if name, ok := tokenNames[t]; ok {
+		return name
+	}
+	return string(t)
+}
+
+// 現在のトークンが閉じ括弧の直前のカンマ(末尾カンマ)のときに呼ぶ
+// オプションで許可されていなければエラーを記録する
+// 閉じ括弧はそのまま読み進めるので、後続のエラーは連鎖しない
+func (p *Parser) checkTrailingComma(end token.TokenType) {
+	if p.options.AllowTrailingComma {
+		return
+	}
+	msg := fmt.Sprintf("unexpected trailing comma before %s", tokenName(end))
+	p.addError(p.peekToken, msg)
+}
+
+// 次のトークンがいずれかのタイプに一致すれば進めてtrueを返す
+// 一致しなければ候補をまとめたエラーを記録する
+func (p *Parser) expectPeekOneOf(types ...token.TokenType) bool {
+	for _, t := range types {
+		if p.peekTokenIs(t) {
+			p.nextToken()
+			return true
+		}
+	}
+	p.peekOneOfError(types)
+	return false
+}
+
+func (p *Parser) peekOneOfError(types []token.TokenType) {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = tokenName(t)
+	}
+	msg := fmt.Sprintf("expected one of [%s], got %s",
+		strings.Join(names, ", "), tokenName(p.peekToken.Type))
+	p.addError(p.peekToken, msg)
+}
+
 func (p *Parser) peekError(t token.TokenType) {
 	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
 		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.addError(p.peekToken, msg)
 }
 
 // -------------------------------------------------------
 
 // 外部にエラーをエクスポート
 
+// 構造化されたパースエラー
+// 位置と種類は、エラーの原因となったトークンのもの
+type ParseError struct {
+	Message   string
+	Line      int
+	Column    int
+	TokenType token.TokenType
+}
+
+func (e ParseError) Error() string {
+	return e.Message
+}
+
+func (p *Parser) addError(tok token.Token, msg string) {
+	p.errors = append(p.errors, ParseError{
+		Message:   msg,
+		Line:      tok.Line,
+		Column:    tok.Column,
+		TokenType: tok.Type,
+	})
+}
+
+// エラーメッセージの一覧を返す
 func (p *Parser) Errors() []string {
-	return p.errors
+	messages := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		messages[i] = err.Message
+	}
+	return messages
+}
+
+// エラーが記録されているかどうか
+func (p *Parser) HasErrors() bool {
+	return len(p.errors) > 0
+}
+
+// 記録されたエラーを消す。スライスの容量はそのまま再利用する
+// 入れ子の深さの上限を超えた状態も解除する
+func (p *Parser) ResetErrors() {
+	p.errors = p.errors[:0]
+	p.tooDeep = false
+	p.depthErrors = 0
+}
+
+// 位置とトークンタイプを含むエラーの一覧を返す
+func (p *Parser) StructuredErrors() []ParseError {
+	errors := make([]ParseError, len(p.errors))
+	copy(errors, p.errors)
+	return errors
 }
@@ -2,10 +2,13 @@ package parser
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/tamurayoshiya/monkey/ast"
 	"github.com/tamurayoshiya/monkey/lexer"
+	"github.com/tamurayoshiya/monkey/token"
 )
 
 // -----------------------------------------------------
@@ -21,6 +24,7 @@ func TestLetStatements(t *testing.T) {
 		{"let x = 5;", "x", 5},
 		{"let y = true;", "y", true},
 		{"let foobar = y;", "foobar", "y"},
+		{"let _1 = 5;", "_1", 5},
 	}
 
 	for _, tt := range tests {
@@ -46,30 +50,1400 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestMultipleLetDeclarations(t *testing.T) {
+	tests := []struct {
+		input               string
+		expectedIdentifiers []string
+		expectedValues      []interface{}
+	}{
+		{"let a = 1, b = 2;", []string{"a", "b"}, []interface{}{1, 2}},
+		{"let a = true, b = a, c = 5", []string{"a", "b", "c"}, []interface{}{true, "a", 5}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != len(tt.expectedIdentifiers) {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d",
+				len(tt.expectedIdentifiers), len(program.Statements))
+		}
+
+		for i, name := range tt.expectedIdentifiers {
+			stmt := program.Statements[i]
+			if !testLetStatement(t, stmt, name) {
+				return
+			}
+
+			val := stmt.(*ast.LetStatement).Value
+			if !testLiteralExpression(t, val, tt.expectedValues[i]) {
+				return
+			}
+		}
+	}
+
+	// 初期化式はそれぞれ独立してパースされる
+	input := "let a = 1 + 2, b = a * 3; b"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if program.String() != "let a = (1 + 2);let b = (a * 3);b" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+// let a, b = 1, 2; は名前と値を並べた1つのMultiLetStatementになる
+func TestMultiNameLetStatements(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedNames  []string
+		expectedValues []interface{}
+		expectedString string
+	}{
+		{"let a, b = 1, 2;", []string{"a", "b"}, []interface{}{1, 2}, "let a, b = 1, 2;"},
+		{"let x, y, z = true, x, 5", []string{"x", "y", "z"}, []interface{}{true, "x", 5}, "let x, y, z = true, x, 5;"},
+		{"let a, b = b, a;", []string{"a", "b"}, []interface{}{"b", "a"}, "let a, b = b, a;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.MultiLetStatement)
+		if !ok {
+			t.Fatalf("statement is not *ast.MultiLetStatement. got=%T", program.Statements[0])
+		}
+		if len(stmt.Names) != len(tt.expectedNames) || len(stmt.Values) != len(tt.expectedValues) {
+			t.Fatalf("wrong counts. names=%d, values=%d", len(stmt.Names), len(stmt.Values))
+		}
+		for i, name := range tt.expectedNames {
+			if stmt.Names[i].Value != name {
+				t.Errorf("stmt.Names[%d] wrong. want=%q, got=%q", i, name, stmt.Names[i].Value)
+			}
+			testLiteralExpression(t, stmt.Values[i], tt.expectedValues[i])
+		}
+		if stmt.String() != tt.expectedString {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expectedString, stmt.String())
+		}
+	}
+
+	// 名前1つのlet文はこれまで通りLetStatementになる
+	program := New(lexer.New("let a = 1, b = 2; let c = 3;")).ParseProgram()
+	for _, stmt := range program.Statements {
+		if _, ok := stmt.(*ast.LetStatement); !ok {
+			t.Errorf("statement is not *ast.LetStatement. got=%T", stmt)
+		}
+	}
+
+	// 関数の値には、それぞれの束縛先の名前が付く
+	program = New(lexer.New("let f, g = fn() { 1 }, fn() { 2 };")).ParseProgram()
+	stmt := program.Statements[0].(*ast.MultiLetStatement)
+	for i, name := range []string{"f", "g"} {
+		fl, ok := stmt.Values[i].(*ast.FunctionLiteral)
+		if !ok || fl.Name != name {
+			t.Errorf("value %d is not a function named %q. got=%s", i, name, stmt.Values[i])
+		}
+	}
+}
+
+func TestMultiNameLetErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let a, b = 1;", "2 names but 1 value"},
+		{"let a, b = 1, 2, 3;", "2 names but 3 values"},
+		{"let a, b, c = 1, 2;", "3 names but 2 values"},
+		{"let a, = 1, 2;", "expected next token to be IDENT, got = instead"},
+		{"let a, b;", "expected next token to be =, got ; instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
 		return false
 	}
-	// Statementインターフェースの変数として受け取ったsを
-	// LetStatementインターフェースの変数に変換
-	letStmt, ok := s.(*ast.LetStatement)
+	// Statementインターフェースの変数として受け取ったsを
+	// LetStatementインターフェースの変数に変換
+	letStmt, ok := s.(*ast.LetStatement)
+	if !ok {
+		t.Errorf("s not *ast.LetStatement. got=%T", s)
+		return false
+	}
+
+	if letStmt.Name.Value != name {
+		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
+		return false
+	}
+
+	if letStmt.Name.TokenLiteral() != name {
+		t.Errorf("letStmt.Name.TokenLiteral() not '%s'. got=%s",
+			name, letStmt.Name.TokenLiteral())
+		return false
+	}
+	return true
+}
+
+func TestParserOptions(t *testing.T) {
+	strict := Options{RequireSemicolons: true}
+	trailing := Options{RequireSemicolons: true, AllowTrailingExpr: true}
+	trailingOnly := Options{AllowTrailingExpr: true}
+
+	tests := []struct {
+		input         string
+		options       Options
+		expectedError string // 空ならエラーなし
+	}{
+		// 末尾の式文だけは;を省略できる
+		{"1 + 1", trailing, ""},
+		{"let x = 1; x * 2", trailing, ""},
+		// ブロックで終わる文と、ブロック内の最後の文は;を省略できる
+		{"if (true) { 1 } 2", trailing, ""},
+		{"let f = fn() { let y = 1; y + 1 }; f()", trailing, ""},
+		// 文と文の間には区切りが必要
+		{"let x = 1 x", trailing, "expected next token to be ;, got IDENT instead"},
+		{"1 2", trailing, "expected next token to be ;, got INT instead"},
+		{"fn() { 1 2 }", trailing, "expected next token to be ;, got INT instead"},
+		// 末尾で省略できるのは式文だけ
+		{"let x = 1", trailing, "expected next token to be ;, got EOF instead"},
+		// AllowTrailingExprだけでは区切りの規則は既定のまま
+		{"1 + 1", trailingOnly, ""},
+		{"let x = 1; x * 2", trailingOnly, ""},
+		{"let x = 1\nlet y = 2", trailingOnly, ""},
+		{"1 2", trailingOnly, ""},
+		{"let x = 1", trailingOnly, ""},
+		// AllowTrailingExprなしでは末尾の;も必要
+		{"1 + 1", strict, "expected next token to be ;, got EOF instead"},
+		{"1 + 1;", strict, ""},
+		// ゼロ値はNewと同じ
+		{"1 2", Options{}, ""},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := NewWithOptions(l, tt.options)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if tt.expectedError == "" {
+			if len(errors) != 0 {
+				t.Errorf("unexpected errors for %q with %+v: %v", tt.input, tt.options, errors)
+			}
+			continue
+		}
+		if len(errors) == 0 {
+			t.Errorf("expected error for %q with %+v, got none", tt.input, tt.options)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+// 改行による文の区切りのテスト
+func TestStatementsEndOnNewline(t *testing.T) {
+	newline := Options{StatementsEndOnNewline: true}
+	strict := Options{StatementsEndOnNewline: true, RequireSemicolons: true}
+
+	tests := []struct {
+		input       string
+		options     Options
+		expected    []string
+		expectError bool
+	}{
+		// 既定では改行をまたいで式が続く
+		{"a\n-b", Options{}, []string{"(a - b)"}, false},
+		{"a\n-b", newline, []string{"a", "(-b)"}, false},
+		{"let x = 1\nlet y = x", newline, []string{"let x = 1;", "let y = x;"}, false},
+		{"f\n(x)", Options{}, []string{"f(x)"}, false},
+		{"f\n(x)", newline, []string{"f", "x"}, false},
+		{"xs\n[0]", newline, []string{"xs", "[0]"}, false},
+		// 演算子や,の後の改行では式は終わらない
+		{"a +\nb", newline, []string{"(a + b)"}, false},
+		{"add(1,\n2)", newline, []string{"add(1, 2)"}, false},
+		// 次の行が演算子で始まる場合はエラーになる
+		{"1 < 2\n< 3", newline, nil, true},
+		// 改行があれば;を必須にしても区切りとして扱う
+		{"let x = 1\nx", strict, []string{"let x = 1;", "x"}, false},
+		{"fn() {\n  let a = 1\n  a\n}", strict, []string{"fn()let a = 1;a"}, false},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := NewWithOptions(l, tt.options)
+		program := p.ParseProgram()
+
+		if tt.expectError {
+			if !p.HasErrors() {
+				t.Errorf("expected errors for %q with %+v, got none", tt.input, tt.options)
+			}
+			continue
+		}
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != len(tt.expected) {
+			t.Errorf("wrong number of statements for %q with %+v. want=%d, got=%d (%s)",
+				tt.input, tt.options, len(tt.expected), len(program.Statements), program.String())
+			continue
+		}
+		for i, s := range tt.expected {
+			if program.Statements[i].String() != s {
+				t.Errorf("statement %d of %q wrong. want=%q, got=%q",
+					i, tt.input, s, program.Statements[i].String())
+			}
+		}
+	}
+
+	// ;が必須で改行もない場合はエラーのまま
+	p := NewWithOptions(lexer.New("let x = 1 x"), strict)
+	p.ParseProgram()
+	if !p.HasErrors() || p.Errors()[0] != "expected next token to be ;, got IDENT instead" {
+		t.Errorf("wrong errors. got=%v", p.Errors())
+	}
+}
+
+// 末尾カンマのテスト
+func TestTrailingCommas(t *testing.T) {
+	lenient := Options{AllowTrailingComma: true}
+
+	tests := []struct {
+		input         string
+		options       Options
+		expected      string
+		expectedError string
+	}{
+		// 許可されていなくてもエラーは1つだけで、リスト自体は組み立てられる
+		{"add(1, 2,)", Options{}, "add(1, 2)", "unexpected trailing comma before RPAREN"},
+		{"[1, 2,]", Options{}, "[1, 2]", "unexpected trailing comma before RBRACKET"},
+		{`{"a": 1,}`, Options{}, `{"a": 1}`, "unexpected trailing comma before RBRACE"},
+		{"fn(x, y,) { x }", Options{}, "fn(x,y)x", "unexpected trailing comma before RPAREN"},
+		{"add(1, 2,)", lenient, "add(1, 2)", ""},
+		{"[1, 2,]", lenient, "[1, 2]", ""},
+		{`{"a": 1,}`, lenient, `{"a": 1}`, ""},
+		{"fn(x, y,) { x }", lenient, "fn(x,y)x", ""},
+		// 末尾カンマのないリストはどちらのモードでも受け付ける
+		{"add(1, 2)", Options{}, "add(1, 2)", ""},
+		{"add(1, 2)", lenient, "add(1, 2)", ""},
+		// カンマだけのリストは許可されない
+		{"add(,)", lenient, "", "no prefix parse function for , found"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := NewWithOptions(l, tt.options)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if tt.expectedError != "" {
+			if len(errors) != 1 {
+				t.Errorf("expected 1 error for %q with %+v, got %d: %v", tt.input, tt.options, len(errors), errors)
+				continue
+			}
+			if errors[0] != tt.expectedError {
+				t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, errors[0])
+			}
+			if tt.expected != "" && program.String() != tt.expected {
+				t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+			}
+			continue
+		}
+		if len(errors) != 0 {
+			t.Errorf("unexpected errors for %q with %+v: %v", tt.input, tt.options, errors)
+			continue
+		}
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestMaximumNestingDepth(t *testing.T) {
+	tests := []struct {
+		input         string
+		options       Options
+		expectedError string
+	}{
+		{
+			strings.Repeat("(", 5000) + "1" + strings.Repeat(")", 5000) + ";",
+			Options{},
+			"maximum nesting depth 1000 exceeded",
+		},
+		{
+			strings.Repeat("[", 5000) + strings.Repeat("]", 5000),
+			Options{},
+			"maximum nesting depth 1000 exceeded",
+		},
+		{
+			strings.Repeat("while (true) { ", 5000) + strings.Repeat("}", 5000),
+			Options{},
+			"maximum nesting depth 1000 exceeded",
+		},
+		{
+			"((((1))));",
+			Options{MaxDepth: 3},
+			"maximum nesting depth 3 exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := NewWithOptions(l, tt.options)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Fatalf("expected exactly 1 error, got %d", len(errors))
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error. want=%q, got=%q", tt.expectedError, errors[0])
+		}
+	}
+
+	// 上限以内の入れ子は通常どおりパースできる
+	input := strings.Repeat("(", 100) + "1" + strings.Repeat(")", 100) + ";"
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if program.String() != "1" {
+		t.Errorf("program.String() wrong. got=%q", program.String())
+	}
+}
+
+func TestStructuredErrors(t *testing.T) {
+	input := "let a = 1;\nlet x = add(1, 2;"
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.StructuredErrors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors, got none")
+	}
+
+	expected := ParseError{
+		Message:   "expected one of [COMMA, RPAREN], got SEMICOLON",
+		Line:      2,
+		Column:    17,
+		TokenType: token.SEMICOLON,
+	}
+	if errors[0] != expected {
+		t.Errorf("wrong structured error. want=%+v, got=%+v", expected, errors[0])
+	}
+
+	// 文字列のAPIも同じメッセージを返す
+	if p.Errors()[0] != expected.Message {
+		t.Errorf("wrong error message. want=%q, got=%q", expected.Message, p.Errors()[0])
+	}
+}
+
+func TestRawStringLiteralExpression(t *testing.T) {
+	input := "`hello\\n\nworld`;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+	if literal.Value != "hello\\n\nworld" {
+		t.Errorf("literal.Value not %q. got=%q", "hello\\n\nworld", literal.Value)
+	}
+
+	l = lexer.New("`unterminated")
+	p = New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 || p.Errors()[0] != "unterminated string literal at line 1" {
+		t.Errorf("wrong errors for unterminated raw string. got=%v", p.Errors())
+	}
+}
+
+func TestUnterminatedStrings(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`"hello`, "unterminated string literal at line 1"},
+		{"let a = 1;\nlet s = \"hello\nworld", "unterminated string literal at line 2"},
+		{`puts("hello);`, "unterminated string literal at line 1"},
+		{`"ends with backslash\`, "unterminated string literal at line 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+
+	// 閉じられていれば改行を含む文字列も使える
+	l := lexer.New("\"hello\nworld\";")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+	if str.Value != "hello\nworld" {
+		t.Errorf("str.Value wrong. want=%q, got=%q", "hello\nworld", str.Value)
+	}
+}
+
+func TestParseStream(t *testing.T) {
+	input := `let x = 5;
+let y = x * 2;
+puts(y);`
+
+	l := lexer.New(input)
+	p := New(l)
+
+	var got []string
+	p.ParseStream(func(stmt ast.Statement) bool {
+		got = append(got, stmt.String())
+		return false
+	})
+	checkParserErrors(t, p)
+
+	if len(got) != 1 || got[0] != "let x = 5;" {
+		t.Fatalf("wrong statements before stopping. got=%q", got)
+	}
+	// 最初の文で止めたので、字句解析器は入力の末尾まで進んでいない
+	if offset, _, _ := l.Position(); offset >= len(input) {
+		t.Errorf("lexer was drained. offset=%d, len(input)=%d", offset, len(input))
+	}
+
+	// もう一度呼ぶと続きからパースする
+	p.ParseStream(func(stmt ast.Statement) bool {
+		got = append(got, stmt.String())
+		return true
+	})
+	checkParserErrors(t, p)
+
+	expected := []string{"let x = 5;", "let y = (x * 2);", "puts(y)"}
+	if len(got) != len(expected) {
+		t.Fatalf("wrong number of statements. want=%d, got=%d (%q)", len(expected), len(got), got)
+	}
+	for i, s := range expected {
+		if got[i] != s {
+			t.Errorf("statement %d wrong. want=%q, got=%q", i, s, got[i])
+		}
+	}
+}
+
+// ブロック式とハッシュリテラルの区別のテスト
+func TestBlockExpressions(t *testing.T) {
+	tests := []struct {
+		input         string
+		isBlock       bool
+		expectedCount int // ブロックなら文の数、ハッシュならペアの数
+		expected      string
+	}{
+		{"{}", false, 0, "{}"},
+		{"{x}", true, 1, "{x}"},
+		{"{x: 1}", false, 1, "{x: 1}"},
+		{`{"a": 1, "b": 2}`, false, 2, `{"a": 1, "b": 2}`},
+		{"{ let x = 1; x + 1 }", true, 2, "{let x = 1;(x + 1)}"},
+		{"{ x; y }", true, 2, "{xy}"},
+		{"{ return 1; }", true, 1, "{return 1;}"},
+		{"{ a ? b : c }", true, 1, "{(a ? b : c)}"},
+		{"{ ; }", true, 0, "{}"},
+		{"{ f(x) }", true, 1, "{f(x)}"},
+		{`{"a": 1}`, false, 1, `{"a": 1}`},
+		{"{ 1 + 1 }", true, 1, "{(1 + 1)}"},
+		{"{ let x = 1; x }", true, 2, "{let x = 1;x}"},
+		// 最初の式の後の : でハッシュと判断する
+		{"{1 + 1: 2}", false, 1, "{(1 + 1): 2}"},
+		{`{f(x): "y"}`, false, 1, `{f(x): "y"}`},
+		{`{ {"a": 1} }`, true, 1, `{{"a": 1}}`},
+		{`{ {} }`, true, 1, "{{}}"},
+		{"{ {x} }", true, 1, "{{x}}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: program.Statements does not contain 1 statement. got=%d",
+				tt.input, len(program.Statements))
+		}
+		exp := program.Statements[0].(*ast.ExpressionStatement).Expression
+
+		if tt.isBlock {
+			block, ok := exp.(*ast.BlockExpression)
+			if !ok {
+				t.Errorf("%q: exp is not ast.BlockExpression. got=%T", tt.input, exp)
+				continue
+			}
+			if len(block.Block.Statements) != tt.expectedCount {
+				t.Errorf("%q: wrong number of statements. want=%d, got=%d",
+					tt.input, tt.expectedCount, len(block.Block.Statements))
+			}
+		} else {
+			hash, ok := exp.(*ast.HashLiteral)
+			if !ok {
+				t.Errorf("%q: exp is not ast.HashLiteral. got=%T", tt.input, exp)
+				continue
+			}
+			if len(hash.Pairs) != tt.expectedCount {
+				t.Errorf("%q: wrong number of pairs. want=%d, got=%d",
+					tt.input, tt.expectedCount, len(hash.Pairs))
+			}
+		}
+
+		if exp.String() != tt.expected {
+			t.Errorf("%q: wrong string. want=%q, got=%q", tt.input, tt.expected, exp.String())
+		}
+	}
+}
+
+// 文を含まない入力のテスト
+func TestEmptyPrograms(t *testing.T) {
+	tests := []string{
+		"",
+		"   ",
+		"\n\t\r\n  \n",
+		";",
+		";;;",
+		" ; \n ; ",
+		"// comment",
+		"// comment\n// another\n",
+		"/* block\ncomment */",
+		"/* a */ ; // b",
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		if p.HasErrors() {
+			t.Errorf("unexpected errors for %q: %v", input, p.Errors())
+			continue
+		}
+		if len(program.Statements) != 0 {
+			t.Errorf("program for %q has %d statements, want 0", input, len(program.Statements))
+		}
+		if program.TokenLiteral() != "" {
+			t.Errorf("program.TokenLiteral() for %q wrong. got=%q", input, program.TokenLiteral())
+		}
+		if program.String() != "" {
+			t.Errorf("program.String() for %q wrong. got=%q", input, program.String())
+		}
+	}
+}
+
+func TestParseExpressionOnly(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))"},
+		{"add(1, 2);", "add(1, 2)"},
+		{`{"port": 8080}["port"]`, `({"port": 8080}["port"])`},
+		{"if (x) { 1 } else { 2 }", "ifx 1else 2"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		exp, err := p.ParseExpressionOnly()
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", tt.input, err)
+			continue
+		}
+		if exp.String() != tt.expected {
+			t.Errorf("wrong expression for %q. want=%q, got=%q", tt.input, tt.expected, exp.String())
+		}
+	}
+}
+
+func TestParseExpressionOnlyErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"let x = 1;", `expected an expression, got statement "let"`},
+		{"return 1;", `expected an expression, got statement "return"`},
+		{"while (x) { x }", `expected an expression, got statement "while"`},
+		{"1; 2", "unexpected INT after expression"},
+		{"1 2", "unexpected INT after expression"},
+		{"x = 1; let y = 2;", "unexpected LET after expression"},
+		{"", "no prefix parse function for EOF found"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		exp, err := p.ParseExpressionOnly()
+		if err == nil {
+			t.Errorf("expected error for %q, got expression %s", tt.input, exp)
+			continue
+		}
+		if err.Error() != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, err)
+		}
+		if exp != nil {
+			t.Errorf("expected nil expression for %q, got %s", tt.input, exp)
+		}
+	}
+}
+
+// ノードのソース上の範囲のテスト
+func TestNodeSpans(t *testing.T) {
+	input := `let x = 5;
+let add = fn(a, b) {
+  a + b
+};
+add(x, [1, 2])[0];
+while (x) { break }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Statements[1].(*ast.LetStatement)
+	fn := let.Value.(*ast.FunctionLiteral)
+	body := fn.Body.Statements[0].(*ast.ExpressionStatement)
+	stmt := program.Statements[2].(*ast.ExpressionStatement)
+	index := stmt.Expression.(*ast.IndexExpression)
+	call := index.Left.(*ast.CallExpression)
+	while := program.Statements[3].(*ast.WhileStatement)
+
+	tests := []struct {
+		name  string
+		node  ast.Node
+		start ast.Position
+		end   ast.Position
+	}{
+		{"program", program, ast.Position{Line: 1, Column: 1}, ast.Position{Line: 6, Column: 19}},
+		{"let x", program.Statements[0], ast.Position{Line: 1, Column: 1}, ast.Position{Line: 1, Column: 10}},
+		{"let add", let, ast.Position{Line: 2, Column: 1}, ast.Position{Line: 4, Column: 2}},
+		{"fn", fn, ast.Position{Line: 2, Column: 11}, ast.Position{Line: 4, Column: 1}},
+		{"a + b", body, ast.Position{Line: 3, Column: 3}, ast.Position{Line: 3, Column: 7}},
+		{"statement", stmt, ast.Position{Line: 5, Column: 1}, ast.Position{Line: 5, Column: 18}},
+		{"index", index, ast.Position{Line: 5, Column: 1}, ast.Position{Line: 5, Column: 17}},
+		{"call", call, ast.Position{Line: 5, Column: 1}, ast.Position{Line: 5, Column: 14}},
+		{"array", call.Arguments[1], ast.Position{Line: 5, Column: 8}, ast.Position{Line: 5, Column: 13}},
+		{"while", while, ast.Position{Line: 6, Column: 1}, ast.Position{Line: 6, Column: 19}},
+		{"break", while.Body.Statements[0], ast.Position{Line: 6, Column: 13}, ast.Position{Line: 6, Column: 13}},
+	}
+
+	for _, tt := range tests {
+		if tt.node.Start() != tt.start {
+			t.Errorf("%s: wrong start. want=%+v, got=%+v", tt.name, tt.start, tt.node.Start())
+		}
+		if tt.node.End() != tt.end {
+			t.Errorf("%s: wrong end. want=%+v, got=%+v", tt.name, tt.end, tt.node.End())
+		}
+	}
+}
+
+func TestHasErrorsAndResetErrors(t *testing.T) {
+	l := lexer.New("let = 5; let x 10;")
+	p := New(l)
+
+	if p.HasErrors() {
+		t.Fatalf("new parser has errors: %v", p.Errors())
+	}
+
+	p.ParseProgram()
+	if !p.HasErrors() {
+		t.Fatalf("expected parser errors, got none")
+	}
+	capacity := cap(p.errors)
+
+	p.ResetErrors()
+	if p.HasErrors() {
+		t.Fatalf("parser still has errors after reset: %v", p.Errors())
+	}
+	if len(p.Errors()) != 0 {
+		t.Errorf("Errors() not empty after reset. got=%v", p.Errors())
+	}
+	if cap(p.errors) != capacity {
+		t.Errorf("reset did not keep capacity. want=%d, got=%d", capacity, cap(p.errors))
+	}
+}
+
+// 複数の候補がある場合のエラーメッセージのテスト
+func TestExpectPeekOneOfErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(1, 2", "expected one of [COMMA, RPAREN], got EOF"},
+		{"add(1 2)", "expected one of [COMMA, RPAREN], got INT"},
+		{"[1, 2", "expected one of [COMMA, RBRACKET], got EOF"},
+		{"fn(x y) { x }", "expected one of [COMMA, RPAREN], got IDENT"},
+		{"add(1;", "expected one of [COMMA, RPAREN], got SEMICOLON"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestErrorRecoveryAfterBrokenStatements(t *testing.T) {
+	input := `
+let = 5;
+let x 10;
+let y = 3;
+`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+
+	expectedErrors := []string{
+		"expected next token to be IDENT, got = instead",
+		"expected next token to be =, got INT instead",
+	}
+	errors := p.Errors()
+	if len(errors) != len(expectedErrors) {
+		t.Fatalf("wrong number of errors. want=%d, got=%d (%q)",
+			len(expectedErrors), len(errors), errors)
+	}
+	for i, msg := range expectedErrors {
+		if errors[i] != msg {
+			t.Errorf("errors[%d] wrong. want=%q, got=%q", i, msg, errors[i])
+		}
+	}
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+			len(program.Statements))
+	}
+	if !testLetStatement(t, program.Statements[0], "y") {
+		return
+	}
+	testLiteralExpression(t, program.Statements[0].(*ast.LetStatement).Value, 3)
+}
+
+// while・for・break・continueの手前でも同期が止まることのテスト
+func TestErrorRecoveryBeforeLoopStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x 5 while (y) { y }", "while (y) { y }"},
+		{"let x 5;\nwhile (y) { y }", "while (y) { y }"},
+		{"let x 5 for (;;) { 1 }", "for (;;) { 1 }"},
+		{"let x 5 break;", "break;"},
+		{"let x 5 continue;", "continue;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 || errors[0] != "expected next token to be =, got INT instead" {
+			t.Errorf("wrong errors for %q. got=%q", tt.input, errors)
+			continue
+		}
+		if program.String() != tt.expected {
+			t.Errorf("wrong program for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+// -----------------------------------------------------
+
+// 呼び出し・添字の連鎖のテスト
+
+func TestParsingCallAndIndexChains(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a()[0]", "(a()[0])"},
+		{"m[1][2]", "((m[1])[2])"},
+		{"f()(g())", "f()(g())"},
+		{"a[0](1)[2]", "((a[0])(1)[2])"},
+		{"-m[1][2]", "(-((m[1])[2]))"},
+		{"f()(x)[0] + 1", "((f()(x)[0]) + 1)"},
+		{"f(1)[0](2)", "(f(1)[0])(2)"},
+		{"-a[0](1)", "(-(a[0])(1))"},
+		{"a[0](1) * b(2)[3]", "((a[0])(1) * (b(2)[3]))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	// 外側のノードほど連鎖の後ろにある演算子になる
+	l := lexer.New("m[1][2]")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	outer, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+	if !testIntegerLiteral(t, outer.Index, 2) {
+		return
+	}
+	inner, ok := outer.Left.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("outer.Left not *ast.IndexExpression. got=%T", outer.Left)
+	}
+	if !testIdentifier(t, inner.Left, "m") {
+		return
+	}
+	testIntegerLiteral(t, inner.Index, 1)
+
+	// a[0](1)[2] は ((a[0])(1))[2] の木になる
+	l = lexer.New("a[0](1)[2]")
+	p = New(l)
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt = program.Statements[0].(*ast.ExpressionStatement)
+	outer, ok = stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.IndexExpression. got=%T", stmt.Expression)
+	}
+	testIntegerLiteral(t, outer.Index, 2)
+	call, ok := outer.Left.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("outer.Left not *ast.CallExpression. got=%T", outer.Left)
+	}
+	if len(call.Arguments) != 1 {
+		t.Fatalf("wrong number of arguments. got=%d", len(call.Arguments))
+	}
+	testIntegerLiteral(t, call.Arguments[0], 1)
+	inner, ok = call.Function.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("call.Function not *ast.IndexExpression. got=%T", call.Function)
+	}
+	testIdentifier(t, inner.Left, "a")
+	testIntegerLiteral(t, inner.Index, 0)
+}
+
+// -----------------------------------------------------
+
+// while文のテスト
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < 10) { x = x + 1; } x`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d",
+			2, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T",
+			program.Statements[0])
+	}
+	if !testInfixExpression(t, stmt.Condition, "x", "<", 10) {
+		return
+	}
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statements. got=%d", len(stmt.Body.Statements))
+	}
+	if _, ok := stmt.Body.Statements[0].(*ast.ExpressionStatement); !ok {
+		t.Fatalf("body statement is not ast.ExpressionStatement. got=%T",
+			stmt.Body.Statements[0])
+	}
+
+	// for文と同じく条件式を()で囲むので、出力をそのまま再パースできる
+	expected := "while ((x < 10)) { (x = (x + 1)) }"
+	if stmt.String() != expected {
+		t.Errorf("stmt.String() wrong. want=%q, got=%q", expected, stmt.String())
+	}
+	reparsed := New(lexer.New(stmt.String()))
+	reparsed.ParseProgram()
+	checkParserErrors(t, reparsed)
+}
+
+func TestWhileStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"while x < 10 { x }", "expected next token to be (, got IDENT instead"},
+		{"while (x < 10 { x }", "expected next token to be ), got { instead"},
+		{"while (x < 10) x", "expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+// -----------------------------------------------------
+
+// for文のテスト
+
+func TestForStatement(t *testing.T) {
+	input := `for (let i = 0; i < 10; i = i + 1) { puts(i); }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T",
+			program.Statements[0])
+	}
+	if !testLetStatement(t, stmt.Init, "i") {
+		return
+	}
+	if !testInfixExpression(t, stmt.Condition, "i", "<", 10) {
+		return
+	}
+	post, ok := stmt.Post.(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt.Post is not ast.ExpressionStatement. got=%T", stmt.Post)
+	}
+	if _, ok := post.Expression.(*ast.AssignExpression); !ok {
+		t.Fatalf("post.Expression is not ast.AssignExpression. got=%T", post.Expression)
+	}
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statements. got=%d", len(stmt.Body.Statements))
+	}
+
+	expected := "for (let i = 0; (i < 10); (i = (i + 1))) { puts(i) }"
+	if stmt.String() != expected {
+		t.Errorf("stmt.String() wrong. want=%q, got=%q", expected, stmt.String())
+	}
+}
+
+func TestForStatementEmptySections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (;;) {}", "for (;;) {  }"},
+		{"for (; i < 3;) { i }", "for (; (i < 3);) { i }"},
+		{"for (i = 0;;) { i }", "for ((i = 0);;) { i }"},
+		{"for (;; i += 1) { i }", "for (;; (i = (i + 1))) { i }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d",
+				1, len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ForStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T",
+				program.Statements[0])
+		}
+		if stmt.String() != tt.expected {
+			t.Errorf("stmt.String() wrong. want=%q, got=%q", tt.expected, stmt.String())
+		}
+	}
+
+	l := lexer.New("for (;;) {}")
+	p := New(l)
+	program := p.ParseProgram()
+	stmt := program.Statements[0].(*ast.ForStatement)
+	if stmt.Init != nil || stmt.Condition != nil || stmt.Post != nil {
+		t.Errorf("empty sections are not nil. got init=%v, condition=%v, post=%v",
+			stmt.Init, stmt.Condition, stmt.Post)
+	}
+	if len(stmt.Body.Statements) != 0 {
+		t.Errorf("body is not empty. got=%d", len(stmt.Body.Statements))
+	}
+}
+
+func TestForStatementErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"for let i = 0; i < 3; i += 1) {}", "expected next token to be (, got LET instead"},
+		{"for (let i = 0 i < 3; i += 1) {}", "expected next token to be ;, got IDENT instead"},
+		{"for (; i < 3 i += 1) {}", "expected next token to be ;, got IDENT instead"},
+		{"for (;; i += 1 {}", "expected next token to be ), got { instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+// -----------------------------------------------------
+
+// break文, continue文のテスト
+
+func TestBreakAndContinueStatements(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedType string
+		expected     string
+	}{
+		{"break;", "*ast.BreakStatement", "break;"},
+		{"continue;", "*ast.ContinueStatement", "continue;"},
+		{"break", "*ast.BreakStatement", "break;"},
+		// ループの外でもパースエラーにはならない
+		{"if (x) { continue; }", "*ast.ExpressionStatement", "ifx continue;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d",
+				1, len(program.Statements))
+		}
+		stmt := program.Statements[0]
+		if fmt.Sprintf("%T", stmt) != tt.expectedType {
+			t.Errorf("stmt is not %s. got=%T", tt.expectedType, stmt)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	input := "while (true) { if (x) { break; } continue; }"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	body := program.Statements[0].(*ast.WhileStatement).Body
+	if len(body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d", len(body.Statements))
+	}
+	if _, ok := body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("body.Statements[1] is not ast.ContinueStatement. got=%T", body.Statements[1])
+	}
+}
+
+// -----------------------------------------------------
+
+// ブロックで終わる式文のテスト
+
+func TestBlockExpressionStatementsWithoutSemicolon(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"if (x) { 1 } 2", []string{"ifx 1", "2"}},
+		{"if (x) { 1 } else { 2 } -3", []string{"ifx 1else 2", "(-3)"}},
+		{"if (x) { 1 } (y)", []string{"ifx 1", "y"}},
+		{"if (x) { 1 }; 2", []string{"ifx 1", "2"}},
+		{"fn(){ 1 } let x = 2;", []string{"fn()1", "let x = 2;"}},
+		{"fn(){ 1 } -2", []string{"fn()1", "(-2)"}},
+		// 同じ行で続く ( は即時呼び出しになる
+		{"fn(){ 1 }() + 2", []string{"(fn()1() + 2)"}},
+		// 文の途中にあるif式はこれまで通り演算子の被演算子になる
+		{"let y = if (x) { 1 } else { 2 } + 3;", []string{"let y = (ifx 1else 2 + 3);"}},
+		{"1 + if (x) { 1 } else { 2 } * 3", []string{"(1 + (ifx 1else 2 * 3))"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != len(tt.expected) {
+			t.Fatalf("%q: program.Statements does not contain %d statements. got=%d",
+				tt.input, len(tt.expected), len(program.Statements))
+		}
+		for i, expected := range tt.expected {
+			if program.Statements[i].String() != expected {
+				t.Errorf("%q: statements[%d] wrong. want=%q, got=%q",
+					tt.input, i, expected, program.Statements[i].String())
+			}
+		}
+	}
+}
+
+// -----------------------------------------------------
+
+// 三項演算子式のテスト
+
+func TestTernaryExpression(t *testing.T) {
+	input := "a > b ? a : b"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.TernaryExpression)
 	if !ok {
-		t.Errorf("s not *ast.LetStatement. got=%T", s)
-		return false
+		t.Fatalf("stmt.Expression is not ast.TernaryExpression. got=%T", stmt.Expression)
+	}
+	if !testInfixExpression(t, exp.Condition, "a", ">", "b") {
+		return
+	}
+	if !testIdentifier(t, exp.Consequence, "a") {
+		return
+	}
+	if !testIdentifier(t, exp.Alternative, "b") {
+		return
+	}
+	if exp.String() != "(a > b ? a : b)" {
+		t.Errorf("exp.String() wrong. got=%q", exp.String())
 	}
 
-	if letStmt.Name.Value != name {
-		t.Errorf("letStmt.Name.Value not '%s'. got=%s", name, letStmt.Name.Value)
-		return false
+	l = lexer.New("a ? b c")
+	p = New(l)
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "expected next token to be :, got IDENT instead" {
+		t.Errorf("wrong errors for missing colon. got=%q", errors)
 	}
+}
 
-	if letStmt.Name.TokenLiteral() != name {
-		t.Errorf("letStmt.Name.TokenLiteral() not '%s'. got=%s",
-			name, letStmt.Name.TokenLiteral())
-		return false
+// -----------------------------------------------------
+
+// 代入式のテスト
+
+func TestComparisonChaining(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a < b < c;", "(a < b < c)"},
+		{"a == b == c;", "(a == b == c)"},
+		{"a < b > c;", "(a < b > c)"},
+		{"a != b == c;", "(a != b == c)"},
+		{"a < b < c < d;", "(a < b < c < d)"},
+		{"a + 1 < b * 2 < c;", "((a + 1) < (b * 2) < c)"},
+		{"a < b < c || d;", "((a < b < c) || d)"},
+		// 優先順位の異なる比較演算子は連鎖しない
+		{"a < b == c;", "((a < b) == c)"},
+		// 括弧で囲んだ比較は連鎖しない
+		{"(a < b) < c;", "((a < b) < c)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5;", "(x = 5)"},
+		{"x = y = 3;", "(x = (y = 3))"},
+		{"x = 1 + 2 * 3;", "(x = (1 + (2 * 3)))"},
+		{"x = a == b;", "(x = (a == b))"},
+		{"f(x = 1);", "f((x = 1))"},
+		{"x += 1;", "(x = (x + 1))"},
+		{"x -= 1 + 2;", "(x = (x - (1 + 2)))"},
+		{"x *= y = 2;", "(x = (x * (y = 2)))"},
+		{"x /= 2 * 3;", "(x = (x / (2 * 3)))"},
+		{"x = y += 2;", "(x = (y = (y + 2)))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		if _, ok := stmt.Expression.(*ast.CallExpression); !ok {
+			assign, ok := stmt.Expression.(*ast.AssignExpression)
+			if !ok {
+				t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T", stmt.Expression)
+			}
+			if !testIdentifier(t, assign.Name, "x") {
+				return
+			}
+		}
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestPostfixExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		operator string
+		expected string
+	}{
+		{"i++;", "++", "(i++)"},
+		{"i--;", "--", "(i--)"},
+		{"-i++;", "++", "(-(i++))"},
+		{"a + i++ * 2;", "++", "(a + ((i++) * 2))"},
+		{"f(i++);", "++", "f((i++))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements does not contain 1 statements. got=%d",
+				len(program.Statements))
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("i++;")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	postfix, ok := stmt.Expression.(*ast.PostfixExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.PostfixExpression. got=%T", stmt.Expression)
+	}
+	if postfix.Operator != "++" {
+		t.Errorf("postfix.Operator is not %q. got=%q", "++", postfix.Operator)
+	}
+	testIdentifier(t, postfix.Left, "i")
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"5 = x;", "invalid assignment target 5"},
+		{"5 = 1;", "invalid assignment target 5"},
+		{"(a + b) = 1;", "invalid assignment target (a + b)"},
+		{"a[0] = 1;", "invalid assignment target (a[0])"},
+		{"5 += 1;", "invalid assignment target 5"},
+		{"5++;", "invalid assignment target 5"},
+		{"a[0]--;", "invalid assignment target (a[0])"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
 	}
-	return true
 }
 
 // -----------------------------------------------------
@@ -227,6 +1601,38 @@ func TestBooleanExpression(t *testing.T) {
 
 // -----------------------------------------------------
 
+// nullリテラルのテスト
+
+func TestNullLiteralExpression(t *testing.T) {
+	input := "null;"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program has not enough statements. got=%d",
+			len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	null, ok := stmt.Expression.(*ast.NullLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.NullLiteral. got=%T", stmt.Expression)
+	}
+	if null.String() != "null" {
+		t.Errorf("null.String() not %q. got=%q", "null", null.String())
+	}
+}
+
+// -----------------------------------------------------
+
 // 前置演算子のテスト
 
 func TestParsingPrefixExpressions(t *testing.T) {
@@ -237,6 +1643,7 @@ func TestParsingPrefixExpressions(t *testing.T) {
 	}{
 		{"!5;", "!", 5},
 		{"-15;", "-", 15},
+		{"~5;", "~", 5},
 		{"!true;", "!", true},
 		{"!false;", "!", false},
 	}
@@ -487,6 +1894,96 @@ func TestFunctionLiteralParsing(t *testing.T) {
 
 // 関数リテラルのパラメータのテスト
 
+func TestFunctionLiteralWithName(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedName string
+		expected     string
+	}{
+		{"let fib = fn(x) { x };", "fib", "let fib = fn<fib>(x)x;"},
+		{"let a = 1, f = fn() { a };", "f", "let a = 1;let f = fn<f>()a;"},
+		{"fn(x) { x };", "", "fn(x)x"},
+		{"f(fn(x) { x });", "", "f(fn(x)x)"},
+		{"let g = h(fn() { 1 });", "", "let g = h(fn()1);"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		var function *ast.FunctionLiteral
+		ast.Walk(program, func(node ast.Node) bool {
+			if fl, ok := node.(*ast.FunctionLiteral); ok {
+				function = fl
+			}
+			return true
+		})
+		if function == nil {
+			t.Fatalf("no function literal found in %q", tt.input)
+		}
+		if function.Name != tt.expectedName {
+			t.Errorf("function.Name wrong for %q. want=%q, got=%q",
+				tt.input, tt.expectedName, function.Name)
+		}
+		if program.String() != tt.expected {
+			t.Errorf("program.String() wrong. want=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestSpreadArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(...args);", "add(...args)"},
+		{"call(...xs, 1);", "call(...xs, 1)"},
+		{"f(1, ...[2, 3], ...g(x));", "f(1, ...[2, 3], ...g(x))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("call(...xs, 1);")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	spread, ok := call.Arguments[0].(*ast.SpreadExpression)
+	if !ok {
+		t.Fatalf("call.Arguments[0] is not ast.SpreadExpression. got=%T", call.Arguments[0])
+	}
+	testIdentifier(t, spread.Value, "xs")
+	testIntegerLiteral(t, call.Arguments[1], 1)
+
+	// 関数呼び出しの引数以外では使えない
+	invalid := []string{"...xs;", "[...xs];", "let y = ...xs;", "f(1 + ...xs);"}
+	for _, input := range invalid {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser error for %q", input)
+			continue
+		}
+		if errors[0] != "spread operator ... is only allowed in call arguments" {
+			t.Errorf("wrong error for %q. got=%q", input, errors[0])
+		}
+	}
+}
+
 func TestFunctionParameterParsing(t *testing.T) {
 	tests := []struct {
 		input          string
@@ -559,23 +2056,102 @@ func TestCallExpressionParsing(t *testing.T) {
 
 // -----------------------------------------------------
 
-// ヘルパー
+// ヘルパー
+
+// テストヘルパー（パース時にエラーが起きていないかチェック）
+func checkParserErrors(t *testing.T, p *Parser) {
+	errors := p.Errors()
+	if len(errors) == 0 {
+		return
+	}
+
+	t.Errorf("parser has %d errors", len(errors))
+	for _, msg := range errors {
+		t.Errorf("parser error: %q", msg)
+	}
+	t.FailNow()
+}
+
+// 数値リテラルテストヘルパー
+func TestRadixIntegerLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xFF;", 255},
+		{"0o17;", 15},
+		{"0b1010;", 10},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements.got=%d",
+				len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestDigitSeparators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"1_000_000;", 1000000},
+		{"0xFF_FF;", 65535},
+		{"0b1010_1010;", 170},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
 
-// テストヘルパー（パース時にエラーが起きていないかチェック）
-func checkParserErrors(t *testing.T, p *Parser) {
-	errors := p.Errors()
-	if len(errors) == 0 {
-		return
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
 	}
 
-	t.Errorf("parser has %d errors", len(errors))
-	for _, msg := range errors {
-		t.Errorf("parser error: %q", msg)
+	invalid := []string{"1__0;", "1_;", "0x_FF;"}
+	for _, input := range invalid {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser error for %q", input)
+			continue
+		}
+		if !strings.HasPrefix(errors[0], "invalid digit separator") &&
+			!strings.Contains(errors[0], token.ILLEGAL) {
+			t.Errorf("wrong error for %q. got=%q", input, errors[0])
+		}
 	}
-	t.FailNow()
 }
 
-// 数値リテラルテストヘルパー
 func testIntegerLiteral(t *testing.T, il ast.Expression, value int64) bool {
 	integ, ok := il.(*ast.IntegerLiteral)
 	if !ok {
@@ -678,6 +2254,233 @@ func testInfixExpression(t *testing.T, exp ast.Expression, left interface{},
 // -----------------------------------------------
 
 // 演算子優先順位解析のテスト
+// メンバーアクセスのテスト
+func TestMemberExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"user.name", `(user["name"])`},
+		{"a.b.c", `((a["b"])["c"])`},
+		{"a.b[0]", `((a["b"])[0])`},
+		{"a[0].b", `((a[0])["b"])`},
+		{"a.f(1)", `(a["f"])(1)`},
+		{"-a.b * c.d", `((-(a["b"])) * (c["d"]))`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("wrong member expression for %q. want=%q, got=%q", tt.input, tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("user.name")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IndexExpression)
+	if !ok {
+		t.Fatalf("exp is not ast.IndexExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, exp.Left, "user")
+
+	str, ok := exp.Index.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp.Index is not ast.StringLiteral. got=%T", exp.Index)
+	}
+	if str.Value != "name" {
+		t.Errorf("str.Value is not %q. got=%q", "name", str.Value)
+	}
+}
+
+func TestMemberExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"user.1", "expected next token to be IDENT, got INT instead"},
+		{`user."name"`, "expected next token to be IDENT, got STRING instead"},
+		{"user.", "expected next token to be IDENT, got EOF instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q, got none", tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("wrong error for %q. want=%q, got=%q", tt.input, tt.expectedError, errors[0])
+		}
+	}
+}
+
+// ast のコンストラクタで組み立てた期待値と、構文解析の結果を構造で比較するテスト
+func TestParseMatchesConstructedAST(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected *ast.Program
+	}{
+		{"1 + 2", ast.Prog(ast.ExprStmt(ast.Infix(ast.Int(1), "+", ast.Int(2))))},
+		{
+			"let x = -a * f(b)[0];",
+			ast.Prog(ast.Let("x", ast.Infix(
+				ast.Prefix("-", ast.Ident("a")),
+				"*",
+				ast.Index(ast.Call(ast.Ident("f"), ast.Ident("b")), ast.Int(0)),
+			))),
+		},
+		{
+			`[1, "two", true]; x`,
+			ast.Prog(
+				ast.ExprStmt(ast.Array(ast.Int(1), ast.Str("two"), ast.Bool(true))),
+				ast.ExprStmt(ast.Ident("x")),
+			),
+		},
+		{`"1"`, ast.Prog(ast.ExprStmt(ast.Str("1")))},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if !ast.Equal(program, tt.expected) {
+			t.Errorf("wrong AST for %q.\nwant=%s\ngot=%s",
+				tt.input, ast.PrettyString(tt.expected), ast.PrettyString(program))
+		}
+	}
+
+	// 文字列の "1" と整数の 1 は等しくない
+	// StringLiteral は引用符付きで文字列化されるので、文字列表現も異なる
+	program := New(lexer.New(`"1"`)).ParseProgram()
+	integer := ast.Prog(ast.ExprStmt(ast.Int(1)))
+	if program.String() == integer.String() {
+		t.Errorf("string literal and integer literal have the same string %q", program.String())
+	}
+	if ast.Equal(program, integer) {
+		t.Errorf("string literal should not equal integer literal")
+	}
+}
+
+// 中置演算子として登録されたトークンには、すべてLOWESTより高い優先順位が必要
+// 優先順位が無いと parseExpression のループが中置解析関数を呼ばず、演算子が黙って無視される
+func TestInfixOperatorsHavePrecedence(t *testing.T) {
+	p := New(lexer.New(""))
+	for tokenType := range p.infixParseFns {
+		if p.precedenceOf(tokenType) <= LOWEST {
+			t.Errorf("infix operator %q has no precedence", tokenType)
+		}
+	}
+}
+
+// 左結合の二項演算子をすべて組み合わせて、優先順位どおりにまとまることを確認する
+// a X b Y c は、Xの優先順位がY以上なら ((a X b) Y c)、そうでなければ (a X (b Y c))
+func TestInfixOperatorPairs(t *testing.T) {
+	binary := map[int]bool{
+		LOGICAL_OR: true, LOGICAL_AND: true, EQUALS: true, LESSGREATER: true,
+		BITWISE: true, SHIFT: true, SUM: true, PRODUCT: true,
+	}
+	var operators []token.TokenType
+	for tokenType, precedence := range precedences {
+		if binary[precedence] {
+			operators = append(operators, tokenType)
+		}
+	}
+	isComparison := func(tokenType token.TokenType) bool {
+		return precedences[tokenType] == EQUALS || precedences[tokenType] == LESSGREATER
+	}
+
+	for _, x := range operators {
+		for _, y := range operators {
+			// 比較演算子どうしは比較の連鎖として別に解析される
+			if isComparison(x) && isComparison(y) {
+				continue
+			}
+			xs, ys := x.OperatorSymbol(), y.OperatorSymbol()
+			input := "a " + xs + " b " + ys + " c"
+			var expected string
+			if precedences[x] >= precedences[y] {
+				expected = "((a " + xs + " b) " + ys + " c)"
+			} else {
+				expected = "(a " + xs + " (b " + ys + " c))"
+			}
+
+			l := lexer.New(input)
+			p := New(l)
+			program := p.ParseProgram()
+			checkParserErrors(t, p)
+
+			if program.String() != expected {
+				t.Errorf("%q: expected=%q, got=%q", input, expected, program.String())
+			}
+		}
+	}
+}
+
+// べき乗の右結合のテスト
+// 文字列表現だけでなく、木の形が 2 ** (3 ** 2) になっていることを確認する
+func TestPowerRightAssociativity(t *testing.T) {
+	l := lexer.New("2 ** 3 ** 2")
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	outer, ok := stmt.Expression.(*ast.InfixExpression)
+	if !ok {
+		t.Fatalf("expression is not ast.InfixExpression. got=%T", stmt.Expression)
+	}
+	if !testIntegerLiteral(t, outer.Left, 2) {
+		return
+	}
+	if outer.Operator != "**" {
+		t.Fatalf("outer operator is not '**'. got=%q", outer.Operator)
+	}
+	// 右辺がさらにべき乗になっている
+	if !testInfixExpression(t, outer.Right, 3, "**", 2) {
+		return
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 ** 3 ** 2", "(2 ** (3 ** 2))"},
+		{"a ** b ** c ** d", "(a ** (b ** (c ** d)))"},
+		{"a ** b * c", "((a ** b) * c)"},
+		{"(a ** b) ** c", "((a ** b) ** c)"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
 func TestOperatorPrecedenceParsing(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -687,10 +2490,66 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"-a * b",
 			"((-a) * b)",
 		},
+		{
+			"--5",
+			"(-(-5))",
+		},
+		{
+			"5--3",
+			"(5 - (-3))",
+		},
 		{
 			"!-a",
 			"(!(-a))",
 		},
+		{
+			"~5",
+			"(~5)",
+		},
+		{
+			"5 & 3",
+			"(5 & 3)",
+		},
+		{
+			"a | b ^ c & d",
+			"(((a | b) ^ c) & d)",
+		},
+		{
+			"a & 1 == 0",
+			"((a & 1) == 0)",
+		},
+		{
+			"a + b | c * d",
+			"((a + b) | (c * d))",
+		},
+		{
+			"a && b | c",
+			"(a && (b | c))",
+		},
+		{
+			"1 << 4",
+			"(1 << 4)",
+		},
+		{
+			"a << 1 >> 2",
+			"((a << 1) >> 2)",
+		},
+		{
+			"a + 1 << b * 2",
+			"((a + 1) << (b * 2))",
+		},
+		{
+			"a << 1 < b >> 1",
+			"((a << 1) < (b >> 1))",
+		},
+		{
+			"a & 1 << 2",
+			"(a & (1 << 2))",
+		},
+		{
+			"-~a * b",
+			"((-(~a)) * b)",
+		},
 		{
 			"a + b + c",
 			"((a + b) + c)",
@@ -791,6 +2650,66 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"-2 ** 2",
+			"(-(2 ** 2))",
+		},
+		{
+			"2 ** -3",
+			"(2 ** (-3))",
+		},
+		{
+			"a * b ** c",
+			"(a * (b ** c))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a == b && c < d",
+			"((a == b) && (c < d))",
+		},
+		{
+			"!a || b",
+			"((!a) || b)",
+		},
+		{
+			"x = a || b",
+			"(x = (a || b))",
+		},
+		{
+			"a > b ? a : b",
+			"(a > b ? a : b)",
+		},
+		{
+			"a ? b : c ? d : e",
+			"(a ? b : (c ? d : e))",
+		},
+		{
+			"a || b ? c + 1 : d * 2",
+			"(a || b ? c + 1 : d * 2)",
+		},
+		{
+			"x = a ? b : c",
+			"(x = (a ? b : c))",
+		},
+		{
+			"a ? b ? 1 : 2 : 3",
+			"(a ? (b ? 1 : 2) : 3)",
+		},
+		{
+			"a | b << 2 + c * d ** e",
+			"(a | (b << (2 + (c * (d ** e)))))",
+		},
+		{
+			"!a == ~b & c || -d",
+			"(((!a) == ((~b) & c)) || (-d))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -829,6 +2748,36 @@ func TestStringLiteralExpression(t *testing.T) {
 	}
 }
 
+// 文字列リテラルの文字列表現は、パースし直すと同じ値になる
+func TestStringLiteralStringRoundTrip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a\"b"`, `"a\"b"`},
+		{`"line\nnext"`, `"line\nnext"`},
+		{`"tab\tend"`, `"tab\tend"`},
+		{`"back\\slash"`, `"back\\slash"`},
+		{"`raw \"quoted\" \\n`", `"raw \"quoted\" \\n"`},
+	}
+
+	for _, tt := range tests {
+		program := New(lexer.New(tt.input)).ParseProgram()
+		literal := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.StringLiteral)
+		if literal.String() != tt.expected {
+			t.Errorf("String() wrong for %s. want=%s, got=%s", tt.input, tt.expected, literal.String())
+		}
+
+		p := New(lexer.New(literal.String()))
+		reparsed := p.ParseProgram()
+		checkParserErrors(t, p)
+		again := reparsed.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.StringLiteral)
+		if again.Value != literal.Value {
+			t.Errorf("round trip changed %s. want=%q, got=%q", tt.input, literal.Value, again.Value)
+		}
+	}
+}
+
 // -----------------------------------------------
 
 // 配列リテラルのテスト
@@ -911,12 +2860,13 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		"two":   2,
 		"three": 3,
 	}
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
 		}
-		expectedValue := expected[literal.String()]
+		expectedValue := expected[literal.Value]
 		testIntegerLiteral(t, value, expectedValue)
 	}
 }
@@ -970,13 +2920,14 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
 		literal, ok := key.(*ast.StringLiteral)
 		if !ok {
 			t.Errorf("key is not ast.StringLiteral. got=%T", key)
 		}
 
-		testFunc, ok := tests[literal.String()]
+		testFunc, ok := tests[literal.Value]
 		if !ok {
 			t.Errorf("No test function for key %q found", literal.String())
 			continue
@@ -986,6 +2937,56 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingHashLiteralsComputedKeys(t *testing.T) {
+	input := `{(a + 1): "x"}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(hash.Pairs) != 1 {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+
+	for _, pair := range hash.Pairs {
+		key, value := pair.Key, pair.Value
+		testInfixExpression(t, key, "a", "+", 1)
+
+		literal, ok := value.(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("value is not ast.StringLiteral. got=%T", value)
+		}
+		if literal.Value != "x" {
+			t.Errorf("literal.Value not %q. got=%q", "x", literal.Value)
+		}
+	}
+}
+
+func TestHashLiteralStringKeepsSourceOrder(t *testing.T) {
+	input := `{"one": 1, "two": 2, "three": 3}`
+	expected := `{"one": 1, "two": 2, "three": 3}`
+
+	// mapの走査順に依存していれば、繰り返すうちに順序が入れ替わる
+	for i := 0; i < 100; i++ {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != expected {
+			t.Fatalf("run %d: expected=%q, got=%q", i, expected, actual)
+		}
+	}
+}
+
 // -----------------------------------------------
 
 // マクロ・リテラルのテスト
@@ -1030,4 +3031,69 @@ func TestMacroLiteralParsing(t *testing.T) {
 
 	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
 
+	if macro.String() != "macro(x, y)(x + y)" {
+		t.Errorf("macro.String() wrong. want=%q, got=%q", "macro(x, y)(x + y)", macro.String())
+	}
+}
+
+// 大きな配列・ハッシュリテラルのパースのベンチマーク (go test -bench . -benchmem)
+//
+// 要素のスライスはappendで倍々に伸びるので、伸長による確保は全体で数十回程度しかない
+// 確保の大半は字句解析器が1文字のトークン(',' や ':')ごとに string(ch) で作っていた
+// リテラルで、あらかじめ作った文字列を使うようにして確保回数が半分になった
+//
+//	                            変更前            変更後
+//	ParseLargeArrayLiteral   20079 allocs/op   10077 allocs/op
+//	ParseLargeHashLiteral    40160 allocs/op   20158 allocs/op
+//
+// 残りは要素ごとのノード(IntegerLiteralなど)の確保で、ASTを作る以上は避けられない
+
+func largeArrayLiteral(n int) string {
+	var out strings.Builder
+	out.WriteString("[")
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		out.WriteString(strconv.Itoa(i))
+	}
+	out.WriteString("];")
+	return out.String()
+}
+
+func BenchmarkParseLargeArrayLiteral(b *testing.B) {
+	input := largeArrayLiteral(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if p.HasErrors() {
+			b.Fatalf("parser errors: %v", p.Errors())
+		}
+	}
+}
+
+func BenchmarkParseLargeHashLiteral(b *testing.B) {
+	var out strings.Builder
+	out.WriteString("{")
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+		fmt.Fprintf(&out, "%d: %d", i, i)
+	}
+	out.WriteString("};")
+	input := out.String()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if p.HasErrors() {
+			b.Fatalf("parser errors: %v", p.Errors())
+		}
+	}
 }
@@ -0,0 +1,102 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/tamurayoshiya/monkey/ast"
+	"github.com/tamurayoshiya/monkey/lexer"
+	"github.com/tamurayoshiya/monkey/parser"
+	"github.com/tamurayoshiya/monkey/token"
+)
+
+// 埋め込み側と同じく、パッケージの外から独自の演算子を登録するテスト
+
+const AT = token.TokenType("@")
+
+func newParserWithAt(input string) *parser.Parser {
+	l := lexer.NewWithOptions(input, lexer.Options{Symbols: map[rune]token.TokenType{'@': AT}})
+	p := parser.New(l)
+	p.RegisterPrefix(AT, func() ast.Expression {
+		expression := &ast.PrefixExpression{
+			Token:    p.CurToken(),
+			Operator: p.CurToken().Literal,
+		}
+		p.NextToken()
+		expression.Right = p.ParseExpression(parser.PREFIX)
+		return expression
+	})
+	return p
+}
+
+func TestRegisterPrefix(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"@x", "(@x)"},
+		{"@x + 1", "((@x) + 1)"},
+		{"-@x", "(-(@x))"},
+		{"f(@a, @b[0])", "f((@a), (@(b[0])))"},
+	}
+
+	for _, tt := range tests {
+		p := newParserWithAt(tt.input)
+		program := p.ParseProgram()
+		if p.HasErrors() {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestRegisterInfix(t *testing.T) {
+	tests := []struct {
+		precedence int
+		input      string
+		expected   string
+	}{
+		{parser.SUM, "a @ b * c", "(a @ (b * c))"},
+		{parser.SUM, "a + b @ c", "((a + b) @ c)"},
+		{parser.PRODUCT, "a + b @ c", "(a + (b @ c))"},
+		{parser.SUM, "@a @ b", "((@a) @ b)"},
+	}
+
+	for _, tt := range tests {
+		p := newParserWithAt(tt.input)
+		p.SetPrecedence(AT, tt.precedence)
+		p.RegisterInfix(AT, func(left ast.Expression) ast.Expression {
+			expression := &ast.InfixExpression{
+				Token:    p.CurToken(),
+				Operator: p.CurToken().Literal,
+				Left:     left,
+			}
+			p.NextToken()
+			expression.Right = p.ParseExpression(tt.precedence)
+			return expression
+		})
+		program := p.ParseProgram()
+		if p.HasErrors() {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestPrefixOnlyRegistration(t *testing.T) {
+	// 中置として登録していなければ、@は式の続きにならず次の文の始まりになる
+	p := newParserWithAt("a @ b")
+	program := p.ParseProgram()
+	if p.HasErrors() {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d", len(program.Statements))
+	}
+	if got := program.Statements[1].String(); got != "(@b)" {
+		t.Errorf("second statement wrong. want=%q, got=%q", "(@b)", got)
+	}
+}
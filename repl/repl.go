@@ -31,7 +31,7 @@ func Start(in io.Reader, out io.Writer) {
 		p := parser.New(l)
 
 		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
+		if p.HasErrors() {
 			printParserErrors(out, p.Errors())
 			continue
 		}
@@ -81,7 +81,7 @@ func Start(in io.Reader, out io.Writer) {
 		p := parser.New(l)
 
 		program := p.ParseProgram()
-		if len(p.Errors()) != 0 {
+		if p.HasErrors() {
 			printParserErrors(out, p.Errors())
 			continue
 		}
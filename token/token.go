@@ -5,6 +5,11 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int // トークンの先頭の文字の行(1始まり)
+	Column  int // トークンの先頭の文字の列(1始まり)
+	// 直前のトークンの終わり(最初のトークンでは入力の先頭)からこのトークンまでの改行の数
+	// 空白とコメントの中の改行を数える。空行が1つあれば2になる
+	LeadingNewlines int
 }
 
 const (
@@ -20,16 +25,44 @@ const (
 	PLUS     = "+"
 	MINUS    = "-"
 	BANG     = "!"
+	TILDE    = "~"
 	ASTERISK = "*"
 	SLASH    = "/"
+	POW      = "**"
+
+	// 複合代入演算子
+	PLUS_EQ     = "+="
+	MINUS_EQ    = "-="
+	ASTERISK_EQ = "*="
+	SLASH_EQ    = "/="
+
+	// 後置インクリメント・デクリメント
+	INC = "++"
+	DEC = "--"
 
 	LT = "<"
 	GT = ">"
 
+	// 論理演算子
+	AND = "&&"
+	OR  = "||"
+
+	// ビット演算子
+	BIT_AND = "&"
+	BIT_OR  = "|"
+	BIT_XOR = "^"
+	SHL     = "<<"
+	SHR     = ">>"
+
 	// デリミタ
 	COMMA     = ","
 	SEMICOLON = ";"
 
+	// 引数の展開
+	ELLIPSIS = "..."
+	// メンバーアクセス
+	DOT = "."
+
 	LPAREN = "("
 	RPAREN = ")"
 	LBRACE = "{"
@@ -43,6 +76,11 @@ const (
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	WHILE    = "WHILE"
+	FOR      = "FOR"
+	BREAK    = "BREAK"
+	CONTINUE = "CONTINUE"
+	NULL     = "NULL"
 
 	EQ     = "=="
 	NOT_EQ = "!="
@@ -54,22 +92,28 @@ const (
 	LBRACKET = "["
 	RBRACKET = "]"
 
-	// ハッシュ
-	COLON = ":"
+	// ハッシュ, 三項演算子
+	COLON    = ":"
+	QUESTION = "?"
 
 	// マクロ
 	MACRO = "MACRO"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
-	"macro":  MACRO,
+	"fn":       FUNCTION,
+	"let":      LET,
+	"true":     TRUE,
+	"false":    FALSE,
+	"if":       IF,
+	"else":     ELSE,
+	"return":   RETURN,
+	"while":    WHILE,
+	"for":      FOR,
+	"break":    BREAK,
+	"continue": CONTINUE,
+	"null":     NULL,
+	"macro":    MACRO,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -78,3 +122,19 @@ func LookupIdent(ident string) TokenType {
 	}
 	return IDENT
 }
+
+// トークンタイプのソース上の表記を返す
+// 演算子とデリミタはタイプの値が記号そのものなのでそれを、キーワードは"fn"などの綴りを返す
+// IDENT, INT, STRING のように表記が決まらないタイプと ILLEGAL, EOF は空文字列を返す
+func (t TokenType) OperatorSymbol() string {
+	switch t {
+	case ILLEGAL, EOF, IDENT, INT, STRING:
+		return ""
+	}
+	for word, tok := range keywords {
+		if tok == t {
+			return word
+		}
+	}
+	return string(t)
+}
@@ -0,0 +1,81 @@
+package token
+
+import "testing"
+
+func TestOperatorSymbol(t *testing.T) {
+	tests := []struct {
+		tokenType TokenType
+		expected  string
+	}{
+		{PLUS, "+"},
+		{POW, "**"},
+		{PLUS_EQ, "+="},
+		{NOT_EQ, "!="},
+		{AND, "&&"},
+		{BIT_AND, "&"},
+		{BIT_XOR, "^"},
+		{SHL, "<<"},
+		{ELLIPSIS, "..."},
+		{LBRACKET, "["},
+		{SEMICOLON, ";"},
+		{FUNCTION, "fn"},
+		{LET, "let"},
+		{CONTINUE, "continue"},
+		{MACRO, "macro"},
+		// 表記が決まらないタイプ
+		{IDENT, ""},
+		{INT, ""},
+		{STRING, ""},
+		{ILLEGAL, ""},
+		{EOF, ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.tokenType.OperatorSymbol(); got != tt.expected {
+			t.Errorf("%s.OperatorSymbol() wrong. want=%q, got=%q", tt.tokenType, tt.expected, got)
+		}
+	}
+}
+
+func TestLookupIdent(t *testing.T) {
+	tests := []struct {
+		ident    string
+		expected TokenType
+	}{
+		{"fn", FUNCTION},
+		{"let", LET},
+		{"true", TRUE},
+		{"false", FALSE},
+		{"if", IF},
+		{"else", ELSE},
+		{"return", RETURN},
+		{"while", WHILE},
+		{"for", FOR},
+		{"break", BREAK},
+		{"continue", CONTINUE},
+		{"null", NULL},
+		{"macro", MACRO},
+		// キーワード以外は識別子
+		{"foobar", IDENT},
+		{"While", IDENT},
+		{"nil", IDENT},
+		{"fnx", IDENT},
+	}
+
+	for _, tt := range tests {
+		if got := LookupIdent(tt.ident); got != tt.expected {
+			t.Errorf("LookupIdent(%q) wrong. want=%q, got=%q", tt.ident, tt.expected, got)
+		}
+	}
+
+	// すべてのキーワードがテストされていることを確認する
+	tested := map[string]bool{}
+	for _, tt := range tests {
+		tested[tt.ident] = true
+	}
+	for word := range keywords {
+		if !tested[word] {
+			t.Errorf("keyword %q is not covered by this test", word)
+		}
+	}
+}
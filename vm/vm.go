@@ -23,6 +23,8 @@ type VM struct {
 	globals     []object.Object
 	frames      []*Frame
 	framesIndex int
+
+	opcodeCounts map[code.Opcode]int // nil unless profiling is enabled
 }
 
 func New(bytecode *compiler.Bytecode) *VM {
@@ -50,6 +52,18 @@ func NewWithGlobalsStore(bytecode *compiler.Bytecode, s []object.Object) *VM {
 	return vm
 }
 
+// EnableProfiling makes Run tally how many times each opcode is executed.
+// The tally is available through OpcodeCounts after the run.
+func (vm *VM) EnableProfiling() {
+	vm.opcodeCounts = make(map[code.Opcode]int)
+}
+
+// OpcodeCounts returns the per-opcode execution counts collected while
+// profiling was enabled, or nil if it never was.
+func (vm *VM) OpcodeCounts() map[code.Opcode]int {
+	return vm.opcodeCounts
+}
+
 func (vm *VM) StackTop() object.Object {
 	if vm.sp == 0 {
 		return nil
@@ -69,6 +83,10 @@ func (vm *VM) Run() error {
 		ins = vm.currentFrame().Instructions()
 		op = code.Opcode(ins[ip])
 
+		if vm.opcodeCounts != nil {
+			vm.opcodeCounts[op]++
+		}
+
 		switch op {
 		case code.OpConstant:
 			constIndex := code.ReadUint16(ins[ip+1:])
@@ -77,13 +95,34 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpConstantWide:
+			constIndex := code.ReadUint32(ins[ip+1:])
+			vm.currentFrame().ip += 4
+			err := vm.push(vm.constants[constIndex])
+			if err != nil {
+				return err
+			}
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor,
+			code.OpShiftLeft, code.OpShiftRight:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
 			}
 		case code.OpPop:
 			vm.pop()
+		case code.OpDup:
+			err := vm.push(vm.stack[vm.sp-1])
+			if err != nil {
+				return err
+			}
+		case code.OpSwap:
+			vm.stack[vm.sp-1], vm.stack[vm.sp-2] = vm.stack[vm.sp-2], vm.stack[vm.sp-1]
+		case code.OpOver:
+			err := vm.push(vm.stack[vm.sp-2])
+			if err != nil {
+				return err
+			}
 		case code.OpTrue:
 			err := vm.push(True)
 			if err != nil {
@@ -109,7 +148,12 @@ func (vm *VM) Run() error {
 			if err != nil {
 				return err
 			}
-		case code.OpJump:
+		case code.OpNot:
+			err := vm.executeNotOperator()
+			if err != nil {
+				return err
+			}
+		case code.OpJump, code.OpLoop:
 			pos := int(code.ReadUint16(ins[ip+1:]))
 			vm.currentFrame().ip = pos - 1
 		case code.OpJumpNotTruthy:
@@ -119,6 +163,15 @@ func (vm *VM) Run() error {
 			if !isTruthy(condition) {
 				vm.currentFrame().ip = pos - 1
 			}
+		case code.OpJumpNotTruthyOrPop, code.OpJumpTruthyOrPop:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+			// the left operand stays on the stack as the result when it decides
+			if isTruthy(vm.StackTop()) == (op == code.OpJumpTruthyOrPop) {
+				vm.currentFrame().ip = pos - 1
+			} else {
+				vm.pop()
+			}
 		case code.OpNull:
 			err := vm.push(Null)
 			if err != nil {
@@ -172,6 +225,11 @@ func (vm *VM) Run() error {
 			}
 		case code.OpReturnValue:
 			returnValue := vm.pop()
+			if vm.framesIndex == 1 {
+				// a top-level return ends the program, like in the evaluator;
+				// the value just popped is left as the last popped element
+				return nil
+			}
 			frame := vm.popFrame()
 			vm.sp = frame.basePointer - 1
 			err := vm.push(returnValue)
@@ -251,6 +309,26 @@ func (vm *VM) executeBinaryIntegerOperation(op code.Opcode, left, right object.O
 		result = leftValue * rightValue
 	case code.OpDiv:
 		result = leftValue / rightValue
+	case code.OpPow:
+		if rightValue < 0 {
+			return fmt.Errorf("negative exponent: %d", rightValue)
+		}
+		result = object.IntegerPow(leftValue, rightValue)
+	case code.OpBitAnd:
+		result = leftValue & rightValue
+	case code.OpBitOr:
+		result = leftValue | rightValue
+	case code.OpBitXor:
+		result = leftValue ^ rightValue
+	case code.OpShiftLeft, code.OpShiftRight:
+		if rightValue < 0 {
+			return fmt.Errorf("negative shift count: %d", rightValue)
+		}
+		if op == code.OpShiftLeft {
+			result = leftValue << rightValue
+		} else {
+			result = leftValue >> rightValue
+		}
 	default:
 		return fmt.Errorf("unknown integer operator: %d", op)
 	}
@@ -275,6 +353,9 @@ func (vm *VM) executeComparison(op code.Opcode) error {
 	if left.Type() == object.INTEGER_OBJ || right.Type() == object.INTEGER_OBJ {
 		return vm.executeIntegerComparison(op, left, right)
 	}
+	if left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ {
+		return vm.executeStringComparison(op, left, right)
+	}
 	switch op {
 	case code.OpEqual:
 		return vm.push(nativeBoolToBooleanObject(right == left))
@@ -301,6 +382,22 @@ func (vm *VM) executeIntegerComparison(op code.Opcode, left, right object.Object
 	}
 }
 
+func (vm *VM) executeStringComparison(op code.Opcode, left, right object.Object) error {
+	// String literals are interned by the compiler, so two operands loaded
+	// from the same constant are the same object and need no byte comparison.
+	// Strings built at runtime fall back to comparing their values.
+	equal := left == right ||
+		left.(*object.String).Value == right.(*object.String).Value
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(equal))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(!equal))
+	default:
+		return fmt.Errorf("unknown operator: %d (%s %s)", op, left.Type(), right.Type())
+	}
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return True
@@ -310,16 +407,7 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 
 func (vm *VM) executeBangOperator() error {
 	operand := vm.pop()
-	switch operand {
-	case True:
-		return vm.push(False)
-	case False:
-		return vm.push(True)
-	case Null:
-		return vm.push(True)
-	default:
-		return vm.push(False)
-	}
+	return vm.push(nativeBoolToBooleanObject(!isTruthy(operand)))
 }
 
 func (vm *VM) executeMinusOperator() error {
@@ -331,8 +419,21 @@ func (vm *VM) executeMinusOperator() error {
 	return vm.push(&object.Integer{Value: -value})
 }
 
+func (vm *VM) executeNotOperator() error {
+	operand := vm.pop()
+	if operand.Type() != object.INTEGER_OBJ {
+		return fmt.Errorf("unsupported type for complement: %s", operand.Type())
+	}
+	value := operand.(*object.Integer).Value
+	return vm.push(&object.Integer{Value: ^value})
+}
+
+// isTruthy matches the evaluator: false, null and 0 are falsy for every
+// jump and for OpBang.
 func isTruthy(obj object.Object) bool {
 	switch obj := obj.(type) {
+	case *object.Integer:
+		return obj.Value != 0
 	case *object.Boolean:
 		return obj.Value
 	case *object.Null:
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/tamurayoshiya/monkey/ast"
+	"github.com/tamurayoshiya/monkey/code"
 	"github.com/tamurayoshiya/monkey/compiler"
 	"github.com/tamurayoshiya/monkey/lexer"
 	"github.com/tamurayoshiya/monkey/object"
@@ -148,6 +149,8 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"2", 2},
 		{"1 + 2", 3},
 		{"1 - 2", -1},
+		{"--5", 5},
+		{"5--3", 8},
 		{"1 * 2", 2},
 		{"4 / 2", 2},
 		{"50 / 2 * 2 + 10 - 5", 55},
@@ -161,6 +164,20 @@ func TestIntegerArithmetic(t *testing.T) {
 		{"-10", -10},
 		{"-50 + 100 + -50", 0},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"~5", -6},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"1 + 2 | 4", 7},
+		{"1 << 4", 16},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"5 ** 0", 1},
+		{"-16 >> 2", -4},
+		{"1 << 4 >> 2", 4},
+		{"~~5", 5},
+		{"-~0", 1},
 	}
 	runVmTests(t, tests)
 }
@@ -186,6 +203,12 @@ func TestBooleanExpressions(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 < 2 < 3", true},
+		{"1 < 3 < 2", false},
+		{"3 > 2 > 1", true},
+		{"1 == 1 == 1", true},
+		{"1 < 2 < 3 < 4", true},
+		{"1 != 2 == 3", false},
 		{"!true", false},
 		{"!false", true},
 		{"!5", false},
@@ -198,6 +221,110 @@ func TestBooleanExpressions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestComparisonChainEvaluatesOperandsOnce(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; 0 < i++ < 5; i", 1},
+		{"let n = 0; let f = fn() { n += 1; 2 }; 1 < f() < 3; n", 1},
+		{"let n = 0; let f = fn() { n += 1; 2 }; f() < f() < f(); n", 2},
+		{"let n = 0; let f = fn() { n += 1; 0 }; 1 < 0 < f(); n", 0},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestNestedComparisonChains(t *testing.T) {
+	tests := []vmTestCase{
+		// operands kept on the stack must not leak into enclosing expressions
+		// or into chains evaluated while an outer chain is in progress
+		{"let a = 1; let b = 2; a < b < 3", true},
+		{"let f = fn(x) { 0 < x < 10; x }; 1 < f(5) < f(7) < 8", true},
+		{"let f = fn(x) { 0 < x < 10; x }; 9 > f(5) > f(7)", false},
+		{"let a = [3 > 2 > 5, 7]; a[1]", 7},
+		{"(1 < 0 < 5) == false", true},
+		{"1 < 2 < 0 < 9", false},
+		{"let f = fn() { 1 < 2 < 0 }; [f(), 4][1]", 4},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestNullLiteral(t *testing.T) {
+	tests := []vmTestCase{
+		{"null", Null},
+		{"let x = null; x", Null},
+		{"null || 5", 5},
+		{"!null", true},
+		{"if (null) { 1 } else { 2 }", 2},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"let sum = 0; let i = 0; while (i < 5) { i += 1; sum += i; } sum", 15},
+		{"let sum = 0; let i = 0; while (true) { i += 1; if (i > 5) { break; } sum += i; } sum", 15},
+		{"let sum = 0; let i = 0; while (i < 5) { i += 1; if (i == 3) { continue; } sum += i; } sum", 12},
+		{"let sum = 0; for (let i = 0; i < 5; i += 1) { if (i == 3) { continue; } sum += i; } sum", 7},
+		{"let sum = 0; for (let i = 0;; i += 1) { if (i > 3) { break; } sum += i; } sum", 6},
+		{`let n = 0;
+		  for (let i = 0; i < 3; i += 1) {
+		    let j = 0;
+		    while (true) { j += 1; if (j > 2) { break; } n += 1; }
+		  }
+		  n`, 6},
+		{`let f = fn(limit) {
+		    let sum = 0;
+		    let i = 0;
+		    while (true) { i += 1; if (i > limit) { break; } sum += i; }
+		    sum
+		  };
+		  f(4)`, 10},
+		{"let f = fn() { let i = 0; while (true) { i += 1; if (i == 3) { return i; } } }; f()", 3},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestTernaryExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"1 > 2 ? 10 : 20", 20},
+		{"2 > 1 ? 10 : 20", 10},
+		{"null ? 1 : 2", 2},
+		{"let a = 5; let b = 3; a > b ? a : b", 5},
+		{"false ? 1 : true ? 2 : 3", 2},
+		{"(true ? 1 : 2) + 10", 11},
+	}
+
+	runVmTests(t, tests)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{`"a" && "b"`, "b"},
+		{"false || 5", 5},
+		{"false && 5", false},
+		{"3 || 5", 3},
+		{"0 || 5", 5},
+		{"0 && 5", 0},
+		{"1 && 0", 0},
+		{"0 || 0", 0},
+		{"-1 || 5", -1},
+		{"if (0) { 1 } else { 2 }", 2},
+		{"!0", true},
+		{"0 ? 1 : 2", 2},
+		{"let x = if (false) { 1 } || 7; x", 7},
+		{"1 > 2 || 3 > 2 && 4", 4},
+		{"let n = 0; let inc = fn() { n = 1; true }; false && inc(); true || inc(); n", 0},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestConditionals(t *testing.T) {
 	tests := []vmTestCase{
 		{"if (true) { 10 }", 10},
@@ -210,6 +337,43 @@ func TestConditionals(t *testing.T) {
 		{"if (1 > 2) { 10 }", Null},
 		{"if (false) { 10 }", Null},
 		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		// branches that end without a value evaluate to null
+		{"let i = 0; if (true) { while (i < 3) { i++; } }; i", 3},
+		{"let i = 0; if (true) { while (i < 3) { i++; } }", Null},
+		{"if (false) { 1 } else { for (let i = 0; i < 2; i += 1) { } }", Null},
+		{"if (true) { let z = 1; }", Null},
+		{"if (false) { 1 } else { let z = 1; }", Null},
+		{"if (true) { }", Null},
+		{"let c = true; let n = 0; while (c) { if (true) { let z = 1; } n++; c = n < 3; }; n", 3},
+	}
+	runVmTests(t, tests)
+}
+
+func TestTopLevelReturn(t *testing.T) {
+	tests := []vmTestCase{
+		{"return 7; 8", 7},
+		{"let x = 1; if (x > 0) { return x + 1; } 10", 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestBlockExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"{ let x = 1; x + 1 }", 2},
+		{"let y = { let a = 2; a * 3 }; y", 6},
+		{"{ 1 } + { 2 }", 3},
+		{"{ let x = 1; }", Null},
+		{"{ ; }", Null},
+		{"let f = fn() { { return 5; }; 10 }; f()", 5},
+		{"let g = fn() { let a = { let b = 2; b * b }; a + 1 }; g()", 5},
+		// return, break and continue leave the enclosing function or loop,
+		// not just the block expression
+		{"fn(){ let y = { return 7; 1 }; 9 }()", 7},
+		{"fn(){ 1 + { return 7; 1 } }()", 7},
+		{"if ({ return 7; true }) { 1 } else { 2 }", 7},
+		{"let i = 0; while (i < 5) { i += 1; let x = { break; 1 }; }; i", 1},
+		{"let i = 0; let n = 0; while (i < 5) { i += 1; let x = { continue; 1 }; n += 1; }; n", 0},
+		{"fn(){ [1, { return 7; 2 }] }()", 7},
 	}
 	runVmTests(t, tests)
 }
@@ -223,6 +387,85 @@ func TestGlobalLetStatements(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestMultiNameLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let a, b = 1, 2; a", 1},
+		{"let a, b = 1, 2; b", 2},
+		{"let a, b = 3, 4; a * 10 + b", 34},
+		{"let a = 1; let b = 2; let a, b = b, a; a * 10 + b", 21},
+		{"let f = fn() { let x, y = 3, 4; x * y }; f()", 12},
+		{"let f = fn(p) { let x, y = p, p + 1; x * y }; f(4)", 20},
+	}
+	runVmTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let x = 1; x = 2; x", 2},
+		{"let x = 1; x = 2", 2},
+		{"let x = 1; let y = 2; x = y = 3; x + y", 6},
+		{"let x = 1; x = x + 10; x", 11},
+		{"let x = 10; x += 5; x -= 3; x *= 2; x /= 4; x", 6},
+		{"let f = fn() { let a = 1; a = a + 1; a }; f()", 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestPostfixExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 5; i++", 5},
+		{"let i = 5; i++; i", 6},
+		{"let i = 5; i--; i", 4},
+		{"let i = 0; let j = i++ + i++; j", 1},
+		{"let i = 0; while (i < 3) { i++; } i", 3},
+		{"let f = fn() { let a = 1; a++; a }; f()", 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestDupAndSwap(t *testing.T) {
+	tests := []struct {
+		instructions []code.Instructions
+		expected     int
+	}{
+		// 7 dup add -> 14
+		{[]code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpDup),
+			code.Make(code.OpAdd),
+			code.Make(code.OpPop),
+		}, 14},
+		// 7 3 swap sub -> 3 - 7
+		{[]code.Instructions{
+			code.Make(code.OpConstant, 0),
+			code.Make(code.OpConstant, 1),
+			code.Make(code.OpSwap),
+			code.Make(code.OpSub),
+			code.Make(code.OpPop),
+		}, -4},
+	}
+
+	for _, tt := range tests {
+		ins := code.Instructions{}
+		for _, in := range tt.instructions {
+			ins = append(ins, in...)
+		}
+		bytecode := &compiler.Bytecode{
+			Instructions: ins,
+			Constants:    []object.Object{&object.Integer{Value: 7}, &object.Integer{Value: 3}},
+		}
+		vm := New(bytecode)
+		err := vm.Run()
+		if err != nil {
+			t.Fatalf("vm error: %s", err)
+		}
+		err = testIntegerObject(int64(tt.expected), vm.LastPoppedStackElem())
+		if err != nil {
+			t.Errorf("testIntegerObject failed: %s", err)
+		}
+	}
+}
+
 func TestStringExpressions(t *testing.T) {
 	tests := []vmTestCase{
 		{`"monkey"`, "monkey"},
@@ -232,6 +475,23 @@ func TestStringExpressions(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestStringComparison(t *testing.T) {
+	tests := []vmTestCase{
+		// both operands come from the same interned constant
+		{`"monkey" == "monkey"`, true},
+		{`"monkey" != "monkey"`, false},
+		{`"monkey" == "banana"`, false},
+		{`"monkey" != "banana"`, true},
+		// computed strings are distinct objects and compared by value
+		{`"mon" + "key" == "monkey"`, true},
+		{`"monkey" != "mon" + "key"`, false},
+		{`"mon" + "key" == "mon" + "keys"`, false},
+		{`let a = "mon"; let b = "key"; a + b == "monkey"`, true},
+	}
+
+	runVmTests(t, tests)
+}
+
 func TestArrayLiterals(t *testing.T) {
 	tests := []vmTestCase{
 		{"[]", []int{}},
@@ -277,6 +537,7 @@ func TestIndexExpressions(t *testing.T) {
 		{"{1: 1, 2: 2}[2]", 2},
 		{"{1: 1}[0]", Null},
 		{"{}[0]", Null},
+		{`let user = {"name": {"first": 5}}; user.name.first`, 5},
 	}
 	runVmTests(t, tests)
 }
@@ -536,3 +797,79 @@ func TestCallingFunctionsWithWrongArguments(t *testing.T) {
 		}
 	}
 }
+
+func TestNegativeShiftCount(t *testing.T) {
+	comp := compiler.New()
+	err := comp.Compile(parse("1 << -1"))
+	if err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	vm := New(comp.Bytecode())
+	err = vm.Run()
+	if err == nil {
+		t.Fatalf("expected VM error but resulted in none.")
+	}
+	if err.Error() != "negative shift count: -1" {
+		t.Fatalf("wrong VM error: got=%q", err)
+	}
+}
+
+func TestWideConstants(t *testing.T) {
+	constants := make([]object.Object, 70000)
+	for i := range constants {
+		constants[i] = &object.Integer{Value: int64(i)}
+	}
+
+	comp := compiler.NewWithState(compiler.NewSymbolTable(), constants)
+	err := comp.Compile(parse("99999 + 1"))
+	if err != nil {
+		t.Fatalf("compile error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	err = vm.Run()
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	testExpectedObject(t, 100000, vm.LastPoppedStackElem())
+}
+
+func TestOpcodeCounts(t *testing.T) {
+	input := `
+	let loop = fn(self, n, acc) {
+		if (n > 0) { self(self, n - 1, acc + n) } else { acc }
+	};
+	loop(loop, 5, 0);
+	`
+
+	comp := compiler.New()
+	err := comp.Compile(parse(input))
+	if err != nil {
+		t.Fatalf("compile error: %s", err)
+	}
+
+	vm := New(comp.Bytecode())
+	if vm.OpcodeCounts() != nil {
+		t.Fatalf("OpcodeCounts should be nil when profiling is disabled")
+	}
+	vm.EnableProfiling()
+	err = vm.Run()
+	if err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	testExpectedObject(t, 15, vm.LastPoppedStackElem())
+
+	counts := vm.OpcodeCounts()
+	expected := map[code.Opcode]int{
+		code.OpAdd:           5,
+		code.OpJump:          5,
+		code.OpJumpNotTruthy: 6,
+		code.OpCall:          6,
+	}
+	for op, want := range expected {
+		if counts[op] != want {
+			def, _ := code.Lookup(byte(op))
+			t.Errorf("wrong count for %s. want=%d, got=%d", def.Name, want, counts[op])
+		}
+	}
+}